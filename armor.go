@@ -0,0 +1,311 @@
+package argon2aes
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Armored messages follow the layout of OpenPGP ASCII armor: a BEGIN line,
+// optional "Key: Value" headers and a blank line, the data in base64
+// wrapped at 64 columns, a line of "=" and the base64 of the CRC-24 of the
+// data, and an END line.
+const (
+	armorBegin   = "-----BEGIN A2A ENCRYPTED MESSAGE-----"
+	armorEnd     = "-----END A2A ENCRYPTED MESSAGE-----"
+	armorColumns = 64
+
+	crc24Init = 0xB704CE
+	crc24Poly = 0x1864CFB
+)
+
+// crc24 updates crc with p, as in RFC 4880 section 6.1.
+func crc24(crc uint32, p []byte) uint32 {
+	for _, b := range p {
+		crc ^= uint32(b) << 16
+		for i := 0; i < 8; i++ {
+			crc <<= 1
+			if crc&0x1000000 != 0 {
+				crc ^= crc24Poly
+			}
+		}
+	}
+	return crc & 0xFFFFFF
+}
+
+// Armor returns data as an armored message with the given headers.
+func Armor(data []byte, headers map[string]string) ([]byte, error) {
+	var b bytes.Buffer
+	w, err := NewArmorWriter(&b, headers)
+	if err != nil {
+		return nil, err
+	}
+	w.Write(data) // a bytes.Buffer does not fail
+	w.Close()
+	return b.Bytes(), nil
+}
+
+// Dearmor returns the data and headers of the first armored message in
+// text, ignoring any text around it.
+func Dearmor(text []byte) ([]byte, map[string]string, error) {
+	r, headers, err := NewArmorReader(bytes.NewReader(text))
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, headers, nil
+}
+
+// IsArmored reports whether b contains the BEGIN line of an armored
+// message.
+func IsArmored(b []byte) bool {
+	return bytes.Contains(b, []byte(armorBegin))
+}
+
+// NewArmorWriter returns a writer that writes the data written to it to w
+// as an armored message with the given headers, which are written in
+// sorted order. Close writes the checksum and END line; it does not close
+// w. The only error is for a header that cannot be read back.
+func NewArmorWriter(w io.Writer, headers map[string]string) (io.WriteCloser, error) {
+	keys := make([]string, 0, len(headers))
+	for key, value := range headers {
+		if !validArmorKey(key) || strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("invalid armor header %q", key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(armorBegin + "\n")
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s: %s\n", key, headers[key])
+	}
+	b.WriteString("\n")
+
+	lines := &lineWriter{w: w}
+	return &armorWriter{
+		w:      w,
+		header: b.String(),
+		lines:  lines,
+		enc:    base64.NewEncoder(base64.StdEncoding, lines),
+		crc:    crc24Init,
+	}, nil
+}
+
+// validArmorKey reports whether key can be read back as a header name.
+func validArmorKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if c := key[i]; c <= ' ' || c > '~' || c == ':' {
+			return false
+		}
+	}
+	return true
+}
+
+type armorWriter struct {
+	w      io.Writer
+	header string // written before the first output
+	lines  *lineWriter
+	enc    io.WriteCloser
+	crc    uint32
+}
+
+func (a *armorWriter) writeHeader() error {
+	if a.header == "" {
+		return nil
+	}
+	_, err := io.WriteString(a.w, a.header)
+	a.header = ""
+	return err
+}
+
+func (a *armorWriter) Write(p []byte) (int, error) {
+	if err := a.writeHeader(); err != nil {
+		return 0, err
+	}
+	n, err := a.enc.Write(p)
+	a.crc = crc24(a.crc, p[:n])
+	return n, err
+}
+
+func (a *armorWriter) Close() error {
+	if err := a.writeHeader(); err != nil {
+		return err
+	}
+	if err := a.enc.Close(); err != nil {
+		return err
+	}
+	if err := a.lines.endLine(); err != nil {
+		return err
+	}
+	sum := []byte{byte(a.crc >> 16), byte(a.crc >> 8), byte(a.crc)}
+	_, err := fmt.Fprintf(a.w, "=%s\n%s\n", base64.StdEncoding.EncodeToString(sum), armorEnd)
+	return err
+}
+
+// lineWriter breaks its output into lines of armorColumns bytes.
+type lineWriter struct {
+	w   io.Writer
+	col int
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := min(armorColumns-l.col, len(p))
+		if _, err := l.w.Write(p[:n]); err != nil {
+			return written, err
+		}
+		written += n
+		l.col += n
+		p = p[n:]
+		if l.col == armorColumns {
+			if err := l.endLine(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// endLine ends a partial line.
+func (l *lineWriter) endLine() error {
+	if l.col == 0 {
+		return nil
+	}
+	l.col = 0
+	_, err := io.WriteString(l.w, "\n")
+	return err
+}
+
+// NewArmorReader returns a reader of the data in the first armored message
+// of r, and its headers. Text before the BEGIN line and after the END line
+// is ignored, as is whitespace around each line, and the base64 may be
+// wrapped at any width. The reader returns an error matching ErrCorrupt if
+// the message is malformed or its checksum does not match.
+func NewArmorReader(r io.Reader) (io.Reader, map[string]string, error) {
+	a := &armorReader{br: bufio.NewReader(r), crc: crc24Init}
+	for {
+		line, err := a.line()
+		if err == io.EOF {
+			return nil, nil, corruptf("no armored message found")
+		} else if err != nil {
+			return nil, nil, err
+		}
+		if line == armorBegin {
+			break
+		}
+	}
+
+	headers := make(map[string]string)
+	for {
+		line, err := a.line()
+		if err == io.EOF {
+			return nil, nil, corruptf("armor has no end line")
+		} else if err != nil {
+			return nil, nil, err
+		}
+		if line == "" {
+			break
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || !validArmorKey(key) {
+			// Base64 has no colons, so the body starts without a
+			// blank line.
+			a.pending, a.hasPending = line, true
+			break
+		}
+		headers[key] = strings.TrimSpace(value)
+	}
+	return a, headers, nil
+}
+
+type armorReader struct {
+	br         *bufio.Reader
+	pending    string // a body line read while looking for headers
+	hasPending bool
+	crc        uint32
+	carry      []byte // base64 short of a whole group
+	buf        []byte
+	out        []byte // decoded but not yet returned
+	err        error  // returned once out is empty
+}
+
+// line returns the next line without surrounding whitespace, or io.EOF
+// at the end of the input.
+func (a *armorReader) line() (string, error) {
+	if a.hasPending {
+		a.hasPending = false
+		return a.pending, nil
+	}
+	line, err := a.br.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	return strings.TrimSpace(line), err
+}
+
+func (a *armorReader) Read(p []byte) (int, error) {
+	for len(a.out) == 0 && a.err == nil {
+		a.err = a.next()
+	}
+	if len(a.out) > 0 {
+		n := copy(p, a.out)
+		a.out = a.out[n:]
+		return n, nil
+	}
+	return 0, a.err
+}
+
+// next decodes the next line of the body, or checks the checksum and
+// returns io.EOF once it reaches it.
+func (a *armorReader) next() error {
+	line, err := a.line()
+	if err == io.EOF {
+		return corruptf("armor has no end line")
+	} else if err != nil {
+		return err
+	}
+
+	switch {
+	case line == armorEnd:
+		return corruptf("armor has no checksum")
+	case strings.HasPrefix(line, "="):
+		sum, err := base64.StdEncoding.DecodeString(line[1:])
+		if err != nil || len(sum) != 3 || len(a.carry) != 0 {
+			return corruptf("invalid armor checksum line")
+		}
+		if uint32(sum[0])<<16|uint32(sum[1])<<8|uint32(sum[2]) != a.crc {
+			return corruptf("armor checksum mismatch")
+		}
+		if end, err := a.line(); err != nil || end != armorEnd {
+			return corruptf("armor has no end line")
+		}
+		return io.EOF
+	}
+
+	a.carry = append(a.carry, line...)
+	k := len(a.carry) / 4 * 4
+	if need := base64.StdEncoding.DecodedLen(k); cap(a.buf) < need {
+		a.buf = make([]byte, need)
+	}
+	n, err := base64.StdEncoding.Decode(a.buf[:cap(a.buf)], a.carry[:k])
+	if err != nil {
+		return corruptf("invalid armor: %v", err)
+	}
+	a.carry = append(a.carry[:0], a.carry[k:]...)
+	a.crc = crc24(a.crc, a.buf[:n])
+	a.out = a.buf[:n]
+	return nil
+}
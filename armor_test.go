@@ -0,0 +1,133 @@
+package argon2aes
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestArmorRoundTrip(t *testing.T) {
+	headers := map[string]string{"Comment": "backup of notes.txt", "Version": "1"}
+
+	for _, size := range []int{0, 1, 47, 48, 49, 1000} {
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(i*131 + 7)
+		}
+
+		armored, err := Armor(data, headers)
+		if err != nil {
+			t.Fatalf("Armor failed: %v", err)
+		}
+		lines := strings.Split(strings.TrimSuffix(string(armored), "\n"), "\n")
+		if lines[0] != armorBegin || lines[1] != "Comment: backup of notes.txt" || lines[2] != "Version: 1" ||
+			lines[3] != "" || lines[len(lines)-1] != armorEnd || !strings.HasPrefix(lines[len(lines)-2], "=") {
+			t.Errorf("Unexpected armor layout:\n%s", armored)
+		}
+		for _, line := range lines {
+			if len(line) > armorColumns {
+				t.Errorf("Line of %d columns: %q", len(line), line)
+			}
+		}
+
+		r, gotHeaders, err := NewArmorReader(iotest.OneByteReader(bytes.NewReader(armored)))
+		if err != nil {
+			t.Fatalf("NewArmorReader failed: %v", err)
+		}
+		decoded, err := io.ReadAll(r)
+		if err != nil || !bytes.Equal(decoded, data) {
+			t.Errorf("Dearmoring %d bytes gave %v, %v", size, decoded, err)
+		}
+		if len(gotHeaders) != 2 || gotHeaders["Comment"] != headers["Comment"] || gotHeaders["Version"] != "1" {
+			t.Errorf("Headers = %v, want %v", gotHeaders, headers)
+		}
+	}
+}
+
+func TestDearmorTolerance(t *testing.T) {
+	data := []byte(strings.Repeat("The quick brown fox jumps over the lazy dog. ", 5))
+	armored, err := Armor(data, nil)
+	if err != nil {
+		t.Fatalf("Armor failed: %v", err)
+	}
+	body := string(armored)
+
+	// The body rewrapped at 50 columns without the blank line
+	lines := strings.Split(body, "\n")
+	joined := strings.Join(lines[2:len(lines)-3], "")
+	var rewrapped strings.Builder
+	rewrapped.WriteString(lines[0] + "\n")
+	for i := 0; i < len(joined); i += 50 {
+		rewrapped.WriteString(joined[i:min(i+50, len(joined))] + "\n")
+	}
+	rewrapped.WriteString(strings.Join(lines[len(lines)-3:], "\n"))
+
+	testCases := []struct {
+		name string
+		text string
+	}{
+		{"Surrounded", "Hi,\n\nhere is the file:\n\n" + body + "\nThanks\n"},
+		{"CRLF", strings.ReplaceAll(body, "\n", "\r\n")},
+		{"Indented", "    " + strings.ReplaceAll(body, "\n", "\n    ")},
+		{"Rewrapped", rewrapped.String()},
+		{"NoFinalNewline", strings.TrimSuffix(body, "\n")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			decoded, _, err := Dearmor([]byte(tc.text))
+			if err != nil || !bytes.Equal(decoded, data) {
+				t.Errorf("Dearmor gave %q, %v", decoded, err)
+			}
+		})
+	}
+}
+
+func TestDearmorErrors(t *testing.T) {
+	armored, err := Armor([]byte("Hello, World!"), nil)
+	if err != nil {
+		t.Fatalf("Armor failed: %v", err)
+	}
+	text := string(armored)
+	lines := strings.Split(text, "\n")
+	checksum := lines[len(lines)-3]
+
+	testCases := []struct {
+		name string
+		text string
+	}{
+		{"NoBegin", "Hello"},
+		{"NoEnd", strings.TrimSuffix(text, armorEnd+"\n")},
+		{"NoChecksum", strings.Replace(text, checksum+"\n", "", 1)},
+		{"BadChecksum", strings.Replace(text, checksum, "=AAAA", 1)},
+		{"ModifiedBody", strings.Replace(text, lines[2], "X"+lines[2][1:], 1)},
+		{"InvalidBase64", strings.Replace(text, lines[2], "*"+lines[2][1:], 1)},
+		{"Truncated", strings.Join(lines[:3], "\n")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, _, err := Dearmor([]byte(tc.text)); !errors.Is(err, ErrCorrupt) {
+				t.Errorf("Expected an error matching ErrCorrupt, got %v", err)
+			}
+		})
+	}
+
+	for _, key := range []string{"", "Two words", "Key:", "Ключ"} {
+		if _, err := Armor(nil, map[string]string{key: "value"}); err == nil {
+			t.Errorf("Expected an error for header %q, but got none", key)
+		}
+	}
+	if _, err := Armor(nil, map[string]string{"Comment": "two\nlines"}); err == nil {
+		t.Error("Expected an error for a header value with a newline, but got none")
+	}
+}
+
+func TestCRC24(t *testing.T) {
+	if got := crc24(crc24Init, []byte("123456789")); got != 0x21CF02 {
+		t.Errorf("crc24(\"123456789\") = %#06x, want 0x21cf02", got)
+	}
+}
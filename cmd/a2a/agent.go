@@ -0,0 +1,437 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/presbrey/argon2aes"
+	"github.com/spf13/pflag"
+)
+
+// agentSockEnv names the environment variable that points a2a commands at
+// a running agent.
+const agentSockEnv = "A2A_AGENT_SOCK"
+
+func agentFlags(fs *pflag.FlagSet, f *flags) {
+	fs.StringVar(&f.socket, "socket", "", "Socket path (default: $"+agentSockEnv+", or a per-user path in the temporary directory)")
+	fs.DurationVar(&f.ttl, "ttl", time.Hour, "Forget each key this long after it was unlocked")
+	fs.DurationVar(&f.idleTimeout, "idle-timeout", 15*time.Minute, "Forget each key when it has not been used for this long")
+	fs.BoolVar(&f.daemon, "daemon", false, "Run the agent in the background")
+}
+
+func runAgent(ctx context.Context, f *flags, args []string) error {
+	if len(args) > 0 {
+		if args[0] != "lock" || len(args) > 1 {
+			return usageErrorf("unexpected arguments: %v", args)
+		}
+		return agentLock(f.socketPath())
+	}
+	if f.ttl <= 0 || f.idleTimeout <= 0 {
+		return usageErrorf("--ttl and --idle-timeout must be positive")
+	}
+
+	path := f.socketPath()
+	if f.daemon {
+		pid, err := startAgent(path, f.ttl, f.idleTimeout)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s=%s; export %s;\necho Agent pid %d;\n", agentSockEnv, path, agentSockEnv, pid)
+		return nil
+	}
+
+	l, err := listenAgent(path)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s=%s; export %s;\n", agentSockEnv, path, agentSockEnv)
+	return newAgent(f.ttl, f.idleTimeout).serve(ctx, l)
+}
+
+// socketPath returns the --socket flag, the socket of the current agent or
+// a default path private to the user.
+func (f *flags) socketPath() string {
+	if f.socket != "" {
+		return f.socket
+	}
+	if path := os.Getenv(agentSockEnv); path != "" {
+		return path
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "a2a-agent.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("a2a-agent-%d", os.Getuid()), "agent.sock")
+}
+
+// listenAgent creates the agent socket, readable and writable only by the
+// user, after removing a stale socket left by an agent that has exited. The
+// directory of the socket is created if needed and must be private to the
+// user, since the default one has a predictable name in a shared directory.
+func listenAgent(path string) (net.Listener, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := checkSocketDir(dir); err != nil {
+		return nil, err
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("an agent is already listening on %s", path)
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	l, err := listenUnix(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// agentRequest is a single request to the agent, sent as one JSON line on
+// its own connection. Op is "get", "put", "drop", "session" or "lock".
+type agentRequest struct {
+	Op     string               `json:"op"`
+	Params *argon2aes.KeyParams `json:"params,omitempty"`
+	Key    []byte               `json:"key,omitempty"`
+	// Session marks a put key as the one to encrypt with.
+	Session bool `json:"session,omitempty"`
+}
+
+type agentResponse struct {
+	Params *argon2aes.KeyParams `json:"params,omitempty"`
+	Key    []byte               `json:"key,omitempty"`
+	Error  string               `json:"error,omitempty"`
+}
+
+// agentKey is a master key cached by the agent.
+type agentKey struct {
+	params   argon2aes.KeyParams
+	key      []byte
+	unlocked time.Time
+	used     time.Time
+}
+
+// agent caches master keys by their key derivation parameters. One of them
+// may be the session key, which encrypt reuses so that only the first file
+// of a session waits for Argon2.
+type agent struct {
+	ttl, idleTimeout time.Duration
+	now              func() time.Time
+
+	mu      sync.Mutex
+	keys    map[string]*agentKey
+	session string
+}
+
+func newAgent(ttl, idleTimeout time.Duration) *agent {
+	return &agent{ttl: ttl, idleTimeout: idleTimeout, now: time.Now, keys: make(map[string]*agentKey)}
+}
+
+func paramsID(p argon2aes.KeyParams) string {
+	return fmt.Sprintf("%s/%d/%d/%d", hex.EncodeToString(p.Salt), p.Time, p.Memory, p.Threads)
+}
+
+// serve answers requests on l until ctx is done, then removes the socket.
+func (a *agent) serve(ctx context.Context, l net.Listener) error {
+	defer a.lock()
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				a.mu.Lock()
+				a.expire()
+				a.mu.Unlock()
+			}
+		}
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go a.handle(conn)
+	}
+}
+
+func (a *agent) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	var req agentRequest
+	var resp agentResponse
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		resp.Error = err.Error()
+	} else {
+		resp = a.do(req)
+	}
+	json.NewEncoder(conn).Encode(resp)
+	clear(req.Key)
+	clear(resp.Key)
+}
+
+func (a *agent) do(req agentRequest) agentResponse {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.expire()
+
+	switch req.Op {
+	case "get":
+		if req.Params == nil {
+			return agentResponse{Error: "missing params"}
+		}
+		if k := a.keys[paramsID(*req.Params)]; k != nil {
+			k.used = a.now()
+			return agentResponse{Key: bytes.Clone(k.key)}
+		}
+		return agentResponse{}
+	case "session":
+		if k := a.keys[a.session]; k != nil {
+			k.used = a.now()
+			return agentResponse{Params: &k.params, Key: bytes.Clone(k.key)}
+		}
+		return agentResponse{}
+	case "put":
+		if req.Params == nil || len(req.Key) == 0 {
+			return agentResponse{Error: "missing params or key"}
+		}
+		id := paramsID(*req.Params)
+		if old := a.keys[id]; old != nil {
+			clear(old.key)
+		}
+		now := a.now()
+		a.keys[id] = &agentKey{params: *req.Params, key: bytes.Clone(req.Key), unlocked: now, used: now}
+		if req.Session {
+			a.session = id
+		}
+		return agentResponse{}
+	case "drop":
+		if req.Params == nil {
+			return agentResponse{Error: "missing params"}
+		}
+		id := paramsID(*req.Params)
+		if k := a.keys[id]; k != nil {
+			clear(k.key)
+			delete(a.keys, id)
+		}
+		if a.session == id {
+			a.session = ""
+		}
+		return agentResponse{}
+	case "lock":
+		a.wipe()
+		return agentResponse{}
+	}
+	return agentResponse{Error: fmt.Sprintf("unknown op %q", req.Op)}
+}
+
+// expire wipes the keys that are past their TTL or idle timeout. a.mu must
+// be held.
+func (a *agent) expire() {
+	now := a.now()
+	for id, k := range a.keys {
+		if now.Sub(k.unlocked) >= a.ttl || now.Sub(k.used) >= a.idleTimeout {
+			clear(k.key)
+			delete(a.keys, id)
+		}
+	}
+}
+
+// wipe forgets every key. a.mu must be held.
+func (a *agent) wipe() {
+	for id, k := range a.keys {
+		clear(k.key)
+		delete(a.keys, id)
+	}
+	a.session = ""
+}
+
+func (a *agent) lock() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.wipe()
+}
+
+// agentCall sends req to the agent listening on path.
+func agentCall(path string, req agentRequest) (agentResponse, error) {
+	var resp agentResponse
+	conn, err := net.DialTimeout("unix", path, 5*time.Second)
+	if err != nil {
+		return resp, fmt.Errorf("cannot reach the agent: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return resp, fmt.Errorf("cannot reach the agent: %v", err)
+	}
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return resp, fmt.Errorf("invalid response from the agent: %v", err)
+	}
+	if resp.Error != "" {
+		return resp, fmt.Errorf("agent: %s", resp.Error)
+	}
+	return resp, nil
+}
+
+func agentLock(path string) error {
+	_, err := agentCall(path, agentRequest{Op: "lock"})
+	return err
+}
+
+// useAgent reports whether the command should get its keys from an agent:
+// one is selected with A2A_AGENT_SOCK and no passphrase was given.
+func (f *flags) useAgent() bool {
+	return os.Getenv(agentSockEnv) != "" && !f.passphraseGiven()
+}
+
+// newAgentReader is argon2aes.NewReaderContext with master keys from the
+// agent. When the agent does not have the key, the passphrase is prompted
+// for, and the derived key is handed to the agent only once it has opened
+// the file, so that a mistyped passphrase is not cached. A cached key that
+// fails is dropped and the passphrase prompted for instead.
+func (f *flags) newAgentReader(ctx context.Context, r io.Reader, opts ...argon2aes.Option) (io.Reader, error) {
+	path := os.Getenv(agentSockEnv)
+	var params argon2aes.KeyParams
+	var derived []byte
+	cached, useCache := false, true
+	keyFunc := func(ctx context.Context, p argon2aes.KeyParams) ([]byte, error) {
+		params = p
+		if useCache {
+			resp, err := agentCall(path, agentRequest{Op: "get", Params: &p})
+			if err != nil {
+				return nil, err
+			}
+			if resp.Key != nil {
+				cached = true
+				return resp.Key, nil
+			}
+		}
+
+		passphrase, err := f.readPassphrase(false)
+		if err != nil {
+			return nil, err
+		}
+		derived, err = argon2aes.DeriveMasterKey(ctx, passphrase, p)
+		return derived, err
+	}
+	opts = append(opts, argon2aes.WithKeyFunc(keyFunc))
+
+	replay := &replayReader{r: r}
+	dr, err := argon2aes.NewReaderContext(ctx, replay, nil, opts...)
+	if errors.Is(err, argon2aes.ErrWrongPassword) && cached {
+		if _, err := agentCall(path, agentRequest{Op: "drop", Params: &params}); err != nil {
+			return nil, err
+		}
+		useCache = false
+		dr, err = argon2aes.NewReaderContext(ctx, replay.replay(), nil, opts...)
+	}
+	replay.stop()
+	if err != nil {
+		return nil, err
+	}
+	if derived != nil {
+		if _, err := agentCall(path, agentRequest{Op: "put", Params: &params, Key: derived}); err != nil {
+			return nil, err
+		}
+	}
+	return dr, nil
+}
+
+// replayReader keeps what is read from r, so that it can be read again,
+// until stop is called.
+type replayReader struct {
+	r       io.Reader
+	buf     []byte
+	stopped bool
+}
+
+func (rr *replayReader) Read(p []byte) (int, error) {
+	n, err := rr.r.Read(p)
+	if !rr.stopped {
+		rr.buf = append(rr.buf, p[:n]...)
+	}
+	return n, err
+}
+
+// replay stops keeping what is read and returns a reader of everything
+// read so far followed by the rest of r.
+func (rr *replayReader) replay() io.Reader {
+	buf := rr.buf
+	rr.stop()
+	return io.MultiReader(bytes.NewReader(buf), rr.r)
+}
+
+func (rr *replayReader) stop() {
+	rr.stopped, rr.buf = true, nil
+}
+
+// agentEncryptOptions returns options that encrypt with the agent's
+// session key. Without one, the passphrase is prompted for and its key,
+// under a new salt, becomes the session key.
+func (f *flags) agentEncryptOptions(ctx context.Context) ([]argon2aes.Option, error) {
+	path := os.Getenv(agentSockEnv)
+	resp, err := agentCall(path, agentRequest{Op: "session"})
+	if err != nil {
+		return nil, err
+	}
+
+	p, key := resp.Params, resp.Key
+	if key == nil {
+		passphrase, err := f.readPassphrase(true)
+		if err != nil {
+			return nil, err
+		}
+		if err := f.checkStrength(passphrase); err != nil {
+			return nil, err
+		}
+		params, err := argon2aes.NewKeyParams()
+		if err != nil {
+			return nil, err
+		}
+		cost := f.keyParams()
+		params.Time, params.Memory, params.Threads = cost.Time, cost.Memory, cost.Threads
+		if key, err = argon2aes.DeriveMasterKey(ctx, passphrase, params); err != nil {
+			return nil, err
+		}
+		if _, err := agentCall(path, agentRequest{Op: "put", Params: &params, Key: key, Session: true}); err != nil {
+			return nil, err
+		}
+		p = &params
+	}
+
+	return []argon2aes.Option{
+		argon2aes.WithKeyParams(*p),
+		argon2aes.WithKeyFunc(func(context.Context, argon2aes.KeyParams) ([]byte, error) {
+			return key, nil
+		}),
+	}, nil
+}
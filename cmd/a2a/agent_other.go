@@ -0,0 +1,23 @@
+//go:build !unix
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+func listenUnix(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
+
+// checkSocketDir accepts any directory, since file ownership and modes
+// work differently on this platform.
+func checkSocketDir(dir string) error {
+	return nil
+}
+
+func startAgent(path string, ttl, idleTimeout time.Duration) (int, error) {
+	return 0, fmt.Errorf("--daemon is not supported on this platform; run the agent in its own window instead")
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/presbrey/argon2aes"
+)
+
+func TestAgent(t *testing.T) {
+	tempDir := t.TempDir()
+	socket := filepath.Join(tempDir, "agent", "agent.sock")
+	t.Setenv(agentSockEnv, socket)
+
+	// A regular file stands in for a missing controlling terminal, so any
+	// attempt to prompt fails.
+	oldTTYPath := ttyPath
+	ttyPath = filepath.Join(tempDir, "tty")
+	defer func() { ttyPath = oldTTYPath }()
+
+	l, err := listenAgent(socket)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- newAgent(time.Hour, time.Hour).serve(ctx, l) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Agent failed: %v", err)
+		}
+	}()
+
+	info, err := os.Stat(socket)
+	if err != nil {
+		t.Fatalf("Failed to stat socket: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("Expected socket mode 0600, got %o", perm)
+	}
+	if _, err := listenAgent(socket); err == nil {
+		t.Error("Expected an error for a second agent, but got none")
+	}
+
+	password := []byte("YWJjMTIzIT8kKiYoKSctPUB+")
+	plaintext := []byte("Hello, World!")
+	inFile := filepath.Join(tempDir, "input.txt")
+	encFile := filepath.Join(tempDir, "encrypted.bin")
+	outFile := filepath.Join(tempDir, "decrypted.txt")
+	if err := os.WriteFile(inFile, plaintext, 0644); err != nil {
+		t.Fatalf("Failed to write input file: %v", err)
+	}
+
+	t.Run("Locked", func(t *testing.T) {
+		if err := run(context.Background(), []string{"encrypt", "-i", inFile, "-o", encFile}); err != errNoTerminal {
+			t.Errorf("Expected %v, got %v", errNoTerminal, err)
+		}
+	})
+
+	// Unlock the agent as a first encrypt would after prompting.
+	p, err := argon2aes.NewKeyParams()
+	if err != nil {
+		t.Fatalf("NewKeyParams failed: %v", err)
+	}
+	key, err := argon2aes.DeriveMasterKey(context.Background(), password, p)
+	if err != nil {
+		t.Fatalf("DeriveMasterKey failed: %v", err)
+	}
+	if _, err := agentCall(socket, agentRequest{Op: "put", Params: &p, Key: key, Session: true}); err != nil {
+		t.Fatalf("Failed to store key: %v", err)
+	}
+
+	t.Run("Unlocked", func(t *testing.T) {
+		if err := run(context.Background(), []string{"encrypt", "-i", inFile, "-o", encFile}); err != nil {
+			t.Fatalf("Encryption with the agent failed: %v", err)
+		}
+		if err := run(context.Background(), []string{"decrypt", "-i", encFile, "-o", outFile}); err != nil {
+			t.Fatalf("Decryption with the agent failed: %v", err)
+		}
+		if _, err := capture("verify", encFile); err != nil {
+			t.Errorf("Verification with the agent failed: %v", err)
+		}
+
+		// The file must not depend on the agent.
+		os.Remove(outFile)
+		if err := run(context.Background(), []string{"decrypt", "-i", encFile, "-o", outFile, "-p", string(password)}); err != nil {
+			t.Fatalf("Decryption with the passphrase failed: %v", err)
+		}
+		decrypted, err := os.ReadFile(outFile)
+		if err != nil {
+			t.Fatalf("Failed to read decrypted file: %v", err)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Errorf("Decrypted text doesn't match original. Got %s, want %s", decrypted, plaintext)
+		}
+	})
+
+	// Keys derived from a prompted passphrase are only cached once they
+	// open the file.
+	t.Run("WrongKey", func(t *testing.T) {
+		passphraseFile := filepath.Join(tempDir, "passphrase")
+		cachedKey := func() []byte {
+			resp, err := agentCall(socket, agentRequest{Op: "get", Params: &p})
+			if err != nil {
+				t.Fatalf("Failed to get key: %v", err)
+			}
+			return resp.Key
+		}
+		open := func(passphrase string) error {
+			if err := os.WriteFile(passphraseFile, []byte(passphrase), 0600); err != nil {
+				t.Fatalf("Failed to write passphrase file: %v", err)
+			}
+			input, err := os.Open(encFile)
+			if err != nil {
+				t.Fatalf("Failed to open encrypted file: %v", err)
+			}
+			defer input.Close()
+			f := &flags{passphraseFile: passphraseFile, passphraseFD: -1}
+			r, err := f.newAgentReader(context.Background(), input)
+			if err != nil {
+				return err
+			}
+			decrypted, err := io.ReadAll(r)
+			if err == nil && !bytes.Equal(decrypted, plaintext) {
+				t.Errorf("Decrypted text doesn't match original. Got %s, want %s", decrypted, plaintext)
+			}
+			return err
+		}
+
+		// A cached key that fails is dropped and the passphrase read instead.
+		wrong := bytes.Repeat([]byte{1}, len(key))
+		if _, err := agentCall(socket, agentRequest{Op: "put", Params: &p, Key: wrong}); err != nil {
+			t.Fatalf("Failed to store key: %v", err)
+		}
+		if err := open(string(password)); err != nil {
+			t.Fatalf("Decryption after a wrong cached key failed: %v", err)
+		}
+		if !bytes.Equal(cachedKey(), key) {
+			t.Error("Expected the wrong cached key to be replaced")
+		}
+
+		// A mistyped passphrase is not cached.
+		if err := run(context.Background(), []string{"agent", "lock"}); err != nil {
+			t.Fatalf("Lock failed: %v", err)
+		}
+		if err := open("wrong" + string(password)); !errors.Is(err, argon2aes.ErrWrongPassword) {
+			t.Fatalf("Expected a wrong password error, got %v", err)
+		}
+		if cachedKey() != nil {
+			t.Error("Expected the key of a wrong passphrase not to be cached")
+		}
+	})
+
+	t.Run("Lock", func(t *testing.T) {
+		if err := run(context.Background(), []string{"agent", "lock"}); err != nil {
+			t.Fatalf("Lock failed: %v", err)
+		}
+		if err := run(context.Background(), []string{"decrypt", "-i", encFile, "-o", outFile}); err != errNoTerminal {
+			t.Errorf("Expected %v, got %v", errNoTerminal, err)
+		}
+	})
+}
+
+func TestAgentExpiry(t *testing.T) {
+	now := time.Now()
+	a := newAgent(time.Hour, 10*time.Minute)
+	a.now = func() time.Time { return now }
+
+	p := argon2aes.KeyParams{Salt: []byte("salt"), Time: 1, Memory: 64, Threads: 1}
+	get := func() []byte {
+		return a.do(agentRequest{Op: "get", Params: &p}).Key
+	}
+	a.do(agentRequest{Op: "put", Params: &p, Key: []byte("key")})
+
+	for i := 0; i < 6; i++ {
+		now = now.Add(9 * time.Minute)
+		if get() == nil {
+			t.Fatalf("Key expired after %d minutes of use", (i+1)*9)
+		}
+	}
+	now = now.Add(7 * time.Minute)
+	if get() != nil {
+		t.Error("Expected the key to expire after its TTL")
+	}
+
+	a.do(agentRequest{Op: "put", Params: &p, Key: []byte("key")})
+	now = now.Add(10 * time.Minute)
+	if get() != nil {
+		t.Error("Expected the key to expire after the idle timeout")
+	}
+}
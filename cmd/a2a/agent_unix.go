@@ -0,0 +1,71 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// listenUnix creates the socket with no permissions for group or others,
+// so that it is never accessible to them even before it is chmodded.
+func listenUnix(path string) (net.Listener, error) {
+	old := syscall.Umask(0177)
+	defer syscall.Umask(old)
+	return net.Listen("unix", path)
+}
+
+// checkSocketDir refuses a directory for the agent socket that another user
+// could have created or could write to: it must be a directory, not a link
+// to one, owned by the user and with mode 0700.
+func checkSocketDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("socket directory %s is not a directory", dir)
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); !ok || int(st.Uid) != os.Getuid() {
+		return fmt.Errorf("socket directory %s is not owned by the current user", dir)
+	}
+	if perm := info.Mode().Perm(); perm != 0700 {
+		return fmt.Errorf("socket directory %s has mode %04o, not 0700", dir, perm)
+	}
+	return nil
+}
+
+// startAgent runs the agent in a new session in the background and waits
+// until it is listening.
+func startAgent(path string, ttl, idleTimeout time.Duration) (int, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+	cmd := exec.Command(exe, "agent", "--socket", path,
+		"--ttl", ttl.String(), "--idle-timeout", idleTimeout.String())
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+	pid := cmd.Process.Pid
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	for i := 0; i < 100; i++ {
+		select {
+		case err := <-done:
+			return 0, fmt.Errorf("agent exited: %v", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return pid, nil
+		}
+	}
+	return 0, fmt.Errorf("agent did not start listening on %s", path)
+}
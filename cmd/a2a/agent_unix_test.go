@@ -0,0 +1,57 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListenAgentDir(t *testing.T) {
+	tempDir := t.TempDir()
+	private := filepath.Join(tempDir, "private")
+	if err := os.Mkdir(private, 0700); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	shared := filepath.Join(tempDir, "shared")
+	if err := os.Mkdir(shared, 0700); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.Chmod(shared, 0755); err != nil {
+		t.Fatalf("Failed to chmod directory: %v", err)
+	}
+	link := filepath.Join(tempDir, "link")
+	if err := os.Symlink(private, link); err != nil {
+		t.Fatalf("Failed to create link: %v", err)
+	}
+	file := filepath.Join(tempDir, "file")
+	if err := os.WriteFile(file, nil, 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	testCases := []struct {
+		name string
+		dir  string
+		ok   bool
+	}{
+		{"New", filepath.Join(tempDir, "new"), true},
+		{"Private", private, true},
+		{"Shared", shared, false},
+		{"Link", link, false},
+		{"File", file, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			l, err := listenAgent(filepath.Join(tc.dir, "agent.sock"))
+			if err == nil {
+				l.Close()
+			}
+			if tc.ok && err != nil {
+				t.Errorf("Expected the socket to be created, got %v", err)
+			} else if !tc.ok && err == nil {
+				t.Error("Expected an error, but got none")
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"time"
+
+	"github.com/presbrey/argon2aes"
+	"github.com/spf13/pflag"
+)
+
+func benchFlags(fs *pflag.FlagSet, f *flags) {
+	fs.IntVar(&f.size, "size", 64, "Amount of data to encrypt and decrypt, in MiB")
+	jobsFlag(fs, f)
+}
+
+// runBench reports how long key derivation takes with the default
+// parameters and how fast data is encrypted and decrypted once the key is
+// known.
+func runBench(ctx context.Context, f *flags, args []string) error {
+	if err := noArgs(args); err != nil {
+		return err
+	}
+	if f.size < 1 {
+		return usageErrorf("size must be at least 1 MiB")
+	}
+	jobs := f.jobs
+	if jobs < 1 {
+		jobs = runtime.NumCPU()
+	}
+
+	passphrase := []byte("benchmark passphrase")
+	data := make([]byte, f.size<<20)
+	var encrypted bytes.Buffer
+	encrypted.Grow(len(data) + len(data)/16)
+
+	start := time.Now()
+	w, err := argon2aes.NewWriterContext(ctx, &encrypted, passphrase, argon2aes.WithJobs(jobs))
+	if err != nil {
+		return err
+	}
+	kdf := time.Since(start)
+
+	start = time.Now()
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	encryptTime := time.Since(start)
+
+	i, err := argon2aes.Inspect(bytes.NewReader(encrypted.Bytes()))
+	if err != nil {
+		return err
+	}
+
+	r, err := argon2aes.NewReaderContext(ctx, bytes.NewReader(encrypted.Bytes()), passphrase, argon2aes.WithJobs(jobs))
+	if err != nil {
+		return err
+	}
+	start = time.Now()
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return err
+	}
+	decryptTime := time.Since(start)
+
+	fmt.Printf("Key derivation: %v (%s, time=%d, memory=%d KiB, threads=%d)\n",
+		kdf.Round(time.Millisecond), i.KDF, i.Time, i.Memory, i.Threads)
+	fmt.Printf("Encrypt:        %.1f MiB/s (%d MiB, %d jobs)\n", float64(f.size)/encryptTime.Seconds(), f.size, jobs)
+	fmt.Printf("Decrypt:        %.1f MiB/s (%d MiB, %d jobs)\n", float64(f.size)/decryptTime.Seconds(), f.size, jobs)
+	return nil
+}
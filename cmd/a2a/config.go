@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/presbrey/argon2aes/pkg/codec"
+	"github.com/spf13/pflag"
+)
+
+// configEnv names the environment variable that selects a config file in
+// place of the default one.
+const configEnv = "A2A_CONFIG"
+
+// configSettings lists the settings a config file may contain. Each is the
+// name of the flag it provides a default for.
+var configSettings = []string{
+	"encoding",
+	"time", "memory", "threads",
+	"pad", "compress",
+	"suffix",
+	"passphrase-env", "passphrase-file",
+}
+
+// passphraseSources are the flags that choose where the passphrase comes
+// from. Setting any of them on the command line overrides the passphrase
+// source of the config file.
+var passphraseSources = []string{"key", "passphrase", "passphrase-env", "passphrase-file", "passphrase-fd", "generate"}
+
+// config is a parsed config file.
+type config struct {
+	path     string
+	settings map[string]string
+}
+
+// configPath returns $A2A_CONFIG, or a2a/config in $XDG_CONFIG_HOME or
+// ~/.config, on every platform. As the XDG spec requires, a relative
+// $XDG_CONFIG_HOME is ignored. The second result reports whether the file
+// must exist.
+func configPath() (string, bool) {
+	if path := os.Getenv(configEnv); path != "" {
+		return path, true
+	}
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if !filepath.IsAbs(dir) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", false
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "a2a", "config"), false
+}
+
+// loadConfig reads the config file. Its absence is not an error unless it
+// was named by A2A_CONFIG.
+func loadConfig() (*config, error) {
+	c := &config{settings: make(map[string]string)}
+	path, required := configPath()
+	if path == "" {
+		return c, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) && !required {
+			return c, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+	c.path = path
+
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("%s:%d: expected name = value", path, n)
+		}
+		if !isConfigSetting(name) {
+			return nil, fmt.Errorf("%s:%d: unknown setting %q", path, n, name)
+		}
+		if err := checkConfigValue(name, value); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+		c.settings[name] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func isConfigSetting(name string) bool {
+	for _, setting := range configSettings {
+		if setting == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkConfigValue validates the settings whose flags are not checked
+// when they are set.
+func checkConfigValue(name, value string) error {
+	switch name {
+	case "encoding":
+		if value != "none" && codec.Lookup(value) == nil {
+			return fmt.Errorf("invalid encoding %q: must be none, %s", value, strings.Join(codec.Names(), ", "))
+		}
+	}
+	return nil
+}
+
+// applyConfig loads the config file and applies it to fs.
+func (f *flags) applyConfig(fs *pflag.FlagSet) error {
+	c, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	f.config = c
+	return c.apply(fs)
+}
+
+// apply sets each flag of fs that was not given on the command line to
+// the value from the config file.
+func (c *config) apply(fs *pflag.FlagSet) error {
+	for _, name := range configSettings {
+		value, ok := c.settings[name]
+		if !ok {
+			continue
+		}
+
+		switch name {
+		case "encoding":
+			// gen and keygen, which have no --armor, use --encoding
+			// for the key rather than ciphertext.
+			if fs.Lookup("armor") == nil || anyChanged(fs, encodingFlagNames...) {
+				continue
+			}
+		case "passphrase-env", "passphrase-file":
+			if anyChanged(fs, passphraseSources...) {
+				continue
+			}
+			if name == "passphrase-file" {
+				value = expandHome(value)
+			}
+		}
+
+		if fs.Lookup(name) == nil || fs.Changed(name) {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("%s: invalid %s: %v", c.path, name, err)
+		}
+	}
+	return nil
+}
+
+func anyChanged(fs *pflag.FlagSet, names ...string) bool {
+	for _, name := range names {
+		if fs.Changed(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandHome replaces a leading ~ in path with the home directory.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+func configFlags(fs *pflag.FlagSet, f *flags) {
+	encodingFlags(fs, f)
+	passphraseFlags(fs, f)
+	writerFlags(fs, f)
+	suffixFlag(fs, f)
+}
+
+// runConfig prints the settings in effect after applying the config file
+// and any flags, in the format of the config file.
+func runConfig(ctx context.Context, f *flags, args []string) error {
+	if len(args) != 1 || args[0] != "show" {
+		return usageErrorf("usage: a2a config show [flags]")
+	}
+	if err := f.resolveEncoding(); err != nil {
+		return err
+	}
+	if f.config.path != "" {
+		fmt.Printf("# %s\n", f.config.path)
+	} else if path, _ := configPath(); path != "" {
+		fmt.Printf("# %s (not found)\n", path)
+	}
+	encoding := "none"
+	if f.codec != nil {
+		encoding = f.codec.Name()
+	}
+	fmt.Printf("encoding = %s\n", encoding)
+	fmt.Printf("time = %d\n", f.argonTime)
+	fmt.Printf("memory = %d\n", f.argonMemory)
+	fmt.Printf("threads = %d\n", f.argonThreads)
+	fmt.Printf("pad = %s\n", f.pad)
+	fmt.Printf("compress = %s\n", f.compress)
+	fmt.Printf("suffix = %s\n", f.suffix)
+	fmt.Printf("passphrase-env = %s\n", f.passphraseEnv)
+	fmt.Printf("passphrase-file = %s\n", f.passphraseFile)
+	return nil
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/presbrey/argon2aes"
+)
+
+func TestConfig(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tempDir)
+	t.Setenv(configEnv, "")
+	t.Setenv(agentSockEnv, "")
+
+	configFile := filepath.Join(tempDir, "a2a", "config")
+	if err := os.MkdirAll(filepath.Dir(configFile), 0700); err != nil {
+		t.Fatalf("Failed to create config dir: %v", err)
+	}
+	passFile := filepath.Join(tempDir, "pass")
+	if err := os.WriteFile(passFile, []byte("YWJjMTIzIT8kKiYoKSctPUB+\n"), 0600); err != nil {
+		t.Fatalf("Failed to write passphrase file: %v", err)
+	}
+	config := "# Server defaults\n" +
+		"encoding = base92\n" +
+		"time = 1\n" +
+		"memory = 1024\n" +
+		"threads = 2\n" +
+		"pad = pow2\n" +
+		"suffix = .a2a\n" +
+		"passphrase-file = " + passFile + "\n"
+	if err := os.WriteFile(configFile, []byte(config), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	t.Run("Show", func(t *testing.T) {
+		out, err := capture("config", "show", "--pad", "padme", "-6")
+		if err != nil {
+			t.Fatalf("config show failed: %v", err)
+		}
+		for _, want := range []string{
+			"# " + configFile + "\n",
+			"encoding = base64\n",
+			"time = 1\n",
+			"memory = 1024\n",
+			"threads = 2\n",
+			"pad = padme\n",
+			"suffix = .a2a\n",
+			"passphrase-file = " + passFile + "\n",
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+			}
+		}
+	})
+
+	t.Run("EncryptDecrypt", func(t *testing.T) {
+		inFile := filepath.Join(tempDir, "notes.txt")
+		plaintext := "Hello, World!"
+		if err := os.WriteFile(inFile, []byte(plaintext), 0644); err != nil {
+			t.Fatalf("Failed to write input file: %v", err)
+		}
+
+		if err := run(context.Background(), []string{"encrypt", "-i", inFile}); err != nil {
+			t.Fatalf("Encryption failed: %v", err)
+		}
+		// info decodes base92 because of the config file too.
+		out, err := capture("info", "--json", inFile+".a2a")
+		if err != nil {
+			t.Fatalf("info failed: %v", err)
+		}
+		var info argon2aes.Info
+		if err := json.Unmarshal([]byte(out), &info); err != nil {
+			t.Fatalf("Failed to parse info: %v", err)
+		}
+		if info.Time != 1 || info.Memory != 1024 || info.Threads != 2 || info.Padding != "pow2" {
+			t.Errorf("Unexpected parameters: %+v", info)
+		}
+
+		os.Remove(inFile)
+		if err := run(context.Background(), []string{"decrypt", "-i", inFile + ".a2a"}); err != nil {
+			t.Fatalf("Decryption failed: %v", err)
+		}
+		decrypted, err := os.ReadFile(inFile)
+		if err != nil {
+			t.Fatalf("Failed to read decrypted file: %v", err)
+		}
+		if string(decrypted) != plaintext {
+			t.Errorf("Decrypted text doesn't match original. Got %s, want %s", decrypted, plaintext)
+		}
+
+		if err := run(context.Background(), []string{"decrypt", "-i", inFile}); err == nil {
+			t.Error("Expected an error for an input without the suffix, but got none")
+		}
+	})
+
+	// The encoding setting is for ciphertext, not keys
+	t.Run("Keygen", func(t *testing.T) {
+		out, err := capture("keygen")
+		if err != nil {
+			t.Fatalf("keygen failed: %v", err)
+		}
+		if key, err := base64.StdEncoding.DecodeString(strings.TrimSuffix(out, "\n")); err != nil || len(key) != 32 {
+			t.Errorf("Expected a base64 key, got %q", out)
+		}
+	})
+
+	t.Run("FlagsOverride", func(t *testing.T) {
+		inFile := filepath.Join(tempDir, "override.txt")
+		outFile := filepath.Join(tempDir, "override.bin")
+		if err := os.WriteFile(inFile, []byte("Hello, World!"), 0644); err != nil {
+			t.Fatalf("Failed to write input file: %v", err)
+		}
+		err := run(context.Background(), []string{"encrypt", "-i", inFile, "-o", outFile,
+			"-p", "zq7Rw!vB3m@Lp", "--time", "2", "--pad", "none"})
+		if err != nil {
+			t.Fatalf("Encryption failed: %v", err)
+		}
+		out, err := capture("info", "--json", outFile)
+		if err != nil {
+			t.Fatalf("info failed: %v", err)
+		}
+		var info argon2aes.Info
+		if err := json.Unmarshal([]byte(out), &info); err != nil {
+			t.Fatalf("Failed to parse info: %v", err)
+		}
+		if info.Time != 2 || info.Memory != 1024 || info.Padding != "none" {
+			t.Errorf("Unexpected parameters: %+v", info)
+		}
+		if _, err := capture("decrypt", "-i", outFile, "-o", filepath.Join(tempDir, "override.out"), "-p", "zq7Rw!vB3m@Lp"); err != nil {
+			t.Errorf("Decryption with the passphrase flag failed: %v", err)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, config := range []string{
+			"colour = blue\n",
+			"encoding = base65\n",
+			"just some text\n",
+		} {
+			if err := os.WriteFile(configFile, []byte(config), 0600); err != nil {
+				t.Fatalf("Failed to write config file: %v", err)
+			}
+			if _, err := capture("config", "show"); err == nil {
+				t.Errorf("Expected an error for config %q, but got none", config)
+			}
+		}
+
+		if err := os.WriteFile(configFile, []byte("time = soon\n"), 0600); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		if _, err := capture("config", "show"); err == nil {
+			t.Error("Expected an error for an invalid time, but got none")
+		}
+	})
+
+	t.Run("ConfigEnv", func(t *testing.T) {
+		t.Setenv(configEnv, filepath.Join(tempDir, "missing"))
+		if _, err := capture("config", "show"); err == nil {
+			t.Error("Expected an error for a missing A2A_CONFIG file, but got none")
+		}
+
+		other := filepath.Join(tempDir, "other")
+		if err := os.WriteFile(other, []byte("compress = gzip\n"), 0600); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		t.Setenv(configEnv, other)
+		out, err := capture("config", "show")
+		if err != nil {
+			t.Fatalf("config show failed: %v", err)
+		}
+		if !strings.Contains(out, "compress = gzip\n") {
+			t.Errorf("Expected the A2A_CONFIG file to be used, got:\n%s", out)
+		}
+	})
+}
+
+func TestConfigPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv(configEnv, "")
+
+	testCases := []struct {
+		name          string
+		xdgConfigHome string
+		want          string
+	}{
+		{"XDG", filepath.Join(home, "xdg"), filepath.Join(home, "xdg", "a2a", "config")},
+		{"Unset", "", filepath.Join(home, ".config", "a2a", "config")},
+		{"Relative", "xdg", filepath.Join(home, ".config", "a2a", "config")},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("XDG_CONFIG_HOME", tc.xdgConfigHome)
+			if path, required := configPath(); path != tc.want || required {
+				t.Errorf("Expected %s, got %s (required %v)", tc.want, path, required)
+			}
+		})
+	}
+
+	t.Setenv(configEnv, filepath.Join(home, "named"))
+	if path, required := configPath(); path != filepath.Join(home, "named") || !required {
+		t.Errorf("Expected the file named by %s, got %s (required %v)", configEnv, path, required)
+	}
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/presbrey/argon2aes"
+	"github.com/spf13/pflag"
+)
+
+func encryptFlags(fs *pflag.FlagSet, f *flags) {
+	newStreamFlags(fs, f)
+	suffixFlag(fs, f)
+}
+
+// newStreamFlags registers the flags shared by encrypt and rekey.
+func newStreamFlags(fs *pflag.FlagSet, f *flags) {
+	ioFlags(fs, f)
+	passphraseFlags(fs, f)
+	encodingFlags(fs, f)
+	jobsFlag(fs, f)
+	writerFlags(fs, f)
+	strengthFlags(fs, f)
+	fs.BoolVar(&f.generate, "generate", false, "Generate a random passphrase and print it to stderr")
+	wordsFlag(fs, f)
+	resultFlag(fs, f)
+}
+
+func decryptFlags(fs *pflag.FlagSet, f *flags) {
+	ioFlags(fs, f)
+	passphraseFlags(fs, f)
+	encodingFlags(fs, f)
+	jobsFlag(fs, f)
+	suffixFlag(fs, f)
+	resultFlag(fs, f)
+	fs.Int64Var(&f.maxSize, "max-size", 0, "Fail when decrypted output exceeds this many bytes (default: 1 GiB for compressed files, otherwise no limit)")
+}
+
+func rekeyFlags(fs *pflag.FlagSet, f *flags) {
+	newStreamFlags(fs, f)
+	fs.StringVar(&f.newKey, "new-key", "", "New encryption key (base64 or base92 encoded)")
+	fs.StringVar(&f.newPassphrase, "new-passphrase", "", "New encryption passphrase")
+}
+
+// writerFlags registers the options recorded in the header of a new stream.
+func writerFlags(fs *pflag.FlagSet, f *flags) {
+	fs.StringVar(&f.pad, "pad", "none", "Pad the plaintext to hide its length: none, padme, pow2 or a block size in bytes")
+	fs.StringVar(&f.compress, "compress", "none", "Compress the plaintext before encrypting: none, flate or gzip")
+	fs.Uint32Var(&f.argonTime, "time", argon2aes.DefaultTime, "Argon2 time cost (number of passes)")
+	fs.Uint32Var(&f.argonMemory, "memory", argon2aes.DefaultMemory, "Argon2 memory cost in KiB")
+	fs.Uint8Var(&f.argonThreads, "threads", argon2aes.DefaultThreads, "Argon2 parallelism")
+}
+
+// keyParams returns the Argon2 cost selected by writerFlags, without a salt.
+func (f *flags) keyParams() argon2aes.KeyParams {
+	return argon2aes.KeyParams{Time: f.argonTime, Memory: f.argonMemory, Threads: f.argonThreads}
+}
+
+// writerOptions returns the options selected by writerFlags.
+func (f *flags) writerOptions() ([]argon2aes.Option, error) {
+	padding, err := argon2aes.ParsePadding(f.pad)
+	if err != nil {
+		return nil, err
+	}
+	compression, err := argon2aes.ParseCompression(f.compress)
+	if err != nil {
+		return nil, err
+	}
+	return []argon2aes.Option{
+		argon2aes.WithPadding(padding),
+		argon2aes.WithCompression(compression),
+		argon2aes.WithJobs(f.jobs),
+		argon2aes.WithKeyParams(f.keyParams()),
+	}, nil
+}
+
+func noArgs(args []string) error {
+	if len(args) > 0 {
+		return usageErrorf("unexpected argument %q", args[0])
+	}
+	return nil
+}
+
+func runEncrypt(ctx context.Context, f *flags, args []string) error {
+	if err := noArgs(args); err != nil {
+		return err
+	}
+	if err := f.resolveEncoding(); err != nil {
+		return err
+	}
+	if err := f.resolveOutput(false); err != nil {
+		return err
+	}
+	if err := f.checkDistinctFiles(); err != nil {
+		return err
+	}
+	opts, err := f.writerOptions()
+	if err != nil {
+		return err
+	}
+	if f.useAgent() && !f.generate {
+		agentOpts, err := f.agentEncryptOptions(ctx)
+		if err != nil {
+			return err
+		}
+		return encrypt(ctx, f, nil, append(opts, agentOpts...)...)
+	}
+	passphrase, err := f.encryptionPassphrase(f.key, f.passphraseGiven(), func() ([]byte, error) {
+		return f.readPassphrase(true)
+	})
+	if err != nil {
+		return err
+	}
+	return encrypt(ctx, f, passphrase, opts...)
+}
+
+func runDecrypt(ctx context.Context, f *flags, args []string) error {
+	if err := noArgs(args); err != nil {
+		return err
+	}
+	if f.generate {
+		return usageErrorf("--generate can only be used when encrypting")
+	}
+	if f.maxSize < 0 {
+		return usageErrorf("--max-size must not be negative")
+	}
+	if err := f.resolveEncoding(); err != nil {
+		return err
+	}
+	if err := f.resolveOutput(true); err != nil {
+		return err
+	}
+	if err := f.checkDistinctFiles(); err != nil {
+		return err
+	}
+	var passphrase []byte
+	if !f.useAgent() {
+		var err error
+		if passphrase, err = f.readPassphrase(false); err != nil {
+			return err
+		}
+	}
+	return decrypt(ctx, f, passphrase)
+}
+
+// runRekey decrypts the input with the current passphrase and encrypts it
+// again with the new one. The plaintext is never written out.
+func runRekey(ctx context.Context, f *flags, args []string) error {
+	if err := noArgs(args); err != nil {
+		return err
+	}
+	if err := f.resolveEncoding(); err != nil {
+		return err
+	}
+	if err := f.resolveOutput(false); err != nil {
+		return err
+	}
+	if err := f.checkDistinctFiles(); err != nil {
+		return err
+	}
+	opts, err := f.writerOptions()
+	if err != nil {
+		return err
+	}
+	passphrase, err := f.readPassphrase(false)
+	if err != nil {
+		return err
+	}
+	given := f.newKey != "" || f.newPassphrase != ""
+	newPassphrase, err := f.encryptionPassphrase(f.newKey, given, func() ([]byte, error) {
+		return readSecret(f.newKey, f.newPassphrase, "Enter new passphrase: ", "Confirm new passphrase: ")
+	})
+	if err != nil {
+		return err
+	}
+	return rekey(ctx, f, passphrase, newPassphrase, opts...)
+}
+
+// encryptionPassphrase returns the passphrase to encrypt with. With
+// --generate it is generated and printed to stderr, which given, set when
+// the passphrase was supplied some other way, forbids. Otherwise it is read
+// with read and, unless it is a key, checked against the strength policy.
+func (f *flags) encryptionPassphrase(key string, given bool, read func() ([]byte, error)) ([]byte, error) {
+	if f.generate {
+		if given {
+			return nil, usageErrorf("cannot use --generate with another passphrase option")
+		}
+		generated, err := argon2aes.GeneratePassphrase(f.words)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(os.Stderr, "Generated passphrase: %s\n", generated)
+		return []byte(generated), nil
+	}
+
+	secret, err := read()
+	if err != nil {
+		return nil, err
+	}
+	if key == "" {
+		if err := f.checkStrength(secret); err != nil {
+			return nil, err
+		}
+	}
+	return secret, nil
+}
+
+// checkDistinctFiles refuses to write the output over the input, which is
+// streamed and would be truncated before it is read.
+func (f *flags) checkDistinctFiles() error {
+	if f.inputFile != "-" && f.outputFile != "-" && sameFile(f.inputFile, f.outputFile) {
+		return usageErrorf("input and output must be different files")
+	}
+	return nil
+}
+
+// sameFile reports whether a and b name the same file, through a link or
+// another path to it.
+func sameFile(a, b string) bool {
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	if errA == nil && errB == nil {
+		return os.SameFile(infoA, infoB)
+	}
+	a, errA = filepath.Abs(a)
+	b, errB = filepath.Abs(b)
+	return errA == nil && errB == nil && a == b
+}
+
+func encrypt(ctx context.Context, f *flags, passphrase []byte, opts ...argon2aes.Option) error {
+	input, err := openInput(ctx, f.inputFile)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	return writeEncrypted(ctx, f, input, passphrase, opts...)
+}
+
+func decrypt(ctx context.Context, f *flags, passphrase []byte) (err error) {
+	input, err := openInput(ctx, f.inputFile)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	decoder, err := f.newDecoder(input)
+	if err != nil {
+		return err
+	}
+	decoder, maxSize := f.outputLimit(decoder)
+	r, err := f.newReader(ctx, decoder, passphrase, argon2aes.WithJobs(f.jobs), argon2aes.WithMaxSize(maxSize))
+	if err != nil {
+		return err
+	}
+
+	output, err := createOutput(f.outputFile)
+	if err != nil {
+		return err
+	}
+	defer removeOnError(f.outputFile, &err)
+	defer output.Close()
+
+	f.processed, err = io.Copy(output, r)
+	if err != nil {
+		return err
+	}
+	return output.Close()
+}
+
+// defaultMaxCompressed limits the output of a compressed file when
+// --max-size is not given, so that a small file cannot expand to fill the
+// disk. The output of an uncompressed file is no larger than the file.
+var defaultMaxCompressed int64 = 1 << 30
+
+// outputLimit returns the limit on the plaintext of the decoded ciphertext
+// r: --max-size, or defaultMaxCompressed if the header of r shows that it
+// is compressed. It returns a reader of all of r, whose start it peeks at.
+func (f *flags) outputLimit(r io.Reader) (io.Reader, int64) {
+	if f.maxSize != 0 {
+		return r, f.maxSize
+	}
+	br := bufio.NewReader(r)
+	start, _ := br.Peek(br.Size()) // errors are returned by later reads
+	if i, err := argon2aes.Inspect(bytes.NewReader(start)); err == nil && i.Compression != argon2aes.CompressNone.String() {
+		return br, defaultMaxCompressed
+	}
+	return br, 0
+}
+
+// newReader opens the decoded ciphertext r with passphrase, or with keys
+// from the agent when it is in use.
+func (f *flags) newReader(ctx context.Context, r io.Reader, passphrase []byte, opts ...argon2aes.Option) (io.Reader, error) {
+	if f.useAgent() {
+		return f.newAgentReader(ctx, r, opts...)
+	}
+	return argon2aes.NewReaderContext(ctx, r, passphrase, opts...)
+}
+
+func rekey(ctx context.Context, f *flags, passphrase, newPassphrase []byte, opts ...argon2aes.Option) error {
+	input, err := openInput(ctx, f.inputFile)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	decoder, err := f.newDecoder(input)
+	if err != nil {
+		return err
+	}
+	r, err := argon2aes.NewReaderContext(ctx, decoder, passphrase, argon2aes.WithJobs(f.jobs))
+	if err != nil {
+		return err
+	}
+	return writeEncrypted(ctx, f, r, newPassphrase, opts...)
+}
+
+// writeEncrypted encrypts everything read from r to the output file,
+// removing the file if anything fails.
+func writeEncrypted(ctx context.Context, f *flags, r io.Reader, passphrase []byte, opts ...argon2aes.Option) (err error) {
+	output, err := createOutput(f.outputFile)
+	if err != nil {
+		return err
+	}
+	defer removeOnError(f.outputFile, &err)
+	defer output.Close()
+
+	encoder := f.newEncoder(output)
+	w, err := argon2aes.NewWriterContext(ctx, encoder, passphrase, opts...)
+	if err != nil {
+		return err
+	}
+	f.processed, err = io.Copy(w, r)
+	if err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	return output.Close()
+}
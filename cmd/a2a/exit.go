@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/presbrey/argon2aes"
+	"github.com/presbrey/argon2aes/pkg/codec"
+)
+
+// Exit codes. Scripts may rely on these, so they must not change.
+const (
+	exitOK            = 0
+	exitError         = 1   // any other failure
+	exitUsage         = 2   // invalid command, flags or arguments
+	exitWrongPassword = 3   // the passphrase or key does not decrypt the input
+	exitCorrupt       = 4   // the input is not valid ciphertext
+	exitIO            = 5   // a file could not be opened, read or written
+	exitCanceled      = 130 // interrupted, as by a shell for SIGINT
+)
+
+// errorCodes name the exit codes in JSON results.
+var errorCodes = map[int]string{
+	exitError:         "error",
+	exitUsage:         "usage",
+	exitWrongPassword: "wrong_password",
+	exitCorrupt:       "corrupt_input",
+	exitIO:            "io",
+	exitCanceled:      "canceled",
+}
+
+// usageError is an error in how a2a was invoked.
+type usageError struct {
+	err error
+}
+
+func usageErrorf(format string, args ...any) error {
+	return &usageError{fmt.Errorf(format, args...)}
+}
+
+func (e *usageError) Error() string { return e.err.Error() }
+
+func (e *usageError) Unwrap() error { return e.err }
+
+// reportedError is an error already printed as part of a JSON result.
+type reportedError struct {
+	err error
+}
+
+func (e *reportedError) Error() string { return e.err.Error() }
+
+func (e *reportedError) Unwrap() error { return e.err }
+
+// exitCode returns the exit status for the error returned by run.
+func exitCode(err error) int {
+	var usage *usageError
+	var pathErr *fs.PathError
+	var errno syscall.Errno
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, context.Canceled):
+		return exitCanceled
+	case errors.As(err, &usage):
+		return exitUsage
+	case errors.Is(err, argon2aes.ErrWrongPassword):
+		return exitWrongPassword
+	case errors.Is(err, argon2aes.ErrCorrupt), errors.Is(err, codec.ErrCorrupt):
+		return exitCorrupt
+	case errors.As(err, &pathErr), errors.As(err, &errno):
+		return exitIO
+	}
+	return exitError
+}
+
+// result is the JSON summary of a command printed with --json.
+type result struct {
+	Operation string  `json:"operation"`
+	Input     string  `json:"input,omitempty"`
+	Output    string  `json:"output,omitempty"`
+	Bytes     int64   `json:"bytes"`
+	Duration  float64 `json:"duration"` // in seconds
+	ExitCode  int     `json:"exit_code"`
+	ErrorCode string  `json:"error_code,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// report prints the result of an operation to stderr, which is kept free
+// for it because stdout may carry the output.
+func (f *flags) report(operation string, start time.Time, err error) error {
+	r := result{
+		Operation: operation,
+		Input:     f.inputFile,
+		Output:    f.outputFile,
+		Bytes:     f.processed,
+		Duration:  time.Since(start).Seconds(),
+		ExitCode:  exitCode(err),
+	}
+	if err != nil {
+		r.ErrorCode = errorCodes[r.ExitCode]
+		r.Error = err.Error()
+	}
+	if encErr := json.NewEncoder(os.Stderr).Encode(r); encErr != nil && err == nil {
+		return encErr
+	}
+	if err != nil {
+		return &reportedError{err}
+	}
+	return nil
+}
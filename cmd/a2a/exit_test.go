@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExitCodes(t *testing.T) {
+	tempDir := t.TempDir()
+	password := "YWJjMTIzIT8kKiYoKSctPUB+"
+	inFile := filepath.Join(tempDir, "input.txt")
+	encFile := filepath.Join(tempDir, "encrypted.bin")
+	truncFile := filepath.Join(tempDir, "truncated.bin")
+	outFile := filepath.Join(tempDir, "output.txt")
+
+	if err := os.WriteFile(inFile, []byte("Hello, World!"), 0644); err != nil {
+		t.Fatalf("Failed to write input file: %v", err)
+	}
+	if err := run(context.Background(), []string{"encrypt", "-i", inFile, "-o", encFile, "-p", password}); err != nil {
+		t.Fatalf("Failed to run encryption: %v", err)
+	}
+	encrypted, err := os.ReadFile(encFile)
+	if err != nil {
+		t.Fatalf("Failed to read encrypted file: %v", err)
+	}
+	if err := os.WriteFile(truncFile, encrypted[:len(encrypted)-5], 0644); err != nil {
+		t.Fatalf("Failed to write truncated file: %v", err)
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	testCases := []struct {
+		name string
+		ctx  context.Context // nil to run with capture
+		args []string
+		code int
+	}{
+		{"OK", nil, []string{"decrypt", "-i", encFile, "-o", outFile, "-p", password}, exitOK},
+		{"UnknownCommand", nil, []string{"frobnicate"}, exitUsage},
+		{"UnknownFlag", nil, []string{"decrypt", "--frobnicate"}, exitUsage},
+		{"ExtraArgument", nil, []string{"decrypt", "extra"}, exitUsage},
+		{"TwoEncodings", nil, []string{"decrypt", "-6", "-9", "-p", password}, exitUsage},
+		{"EncodingAndShorthand", nil, []string{"decrypt", "--encoding", "hex", "-6", "-p", password}, exitUsage},
+		{"UnknownEncoding", nil, []string{"decrypt", "--encoding", "rot13", "-p", password}, exitUsage},
+		{"NotArmored", nil, []string{"decrypt", "--armor", "-i", inFile, "-o", outFile, "-p", password}, exitCorrupt},
+		{"BadPaper", nil, []string{"paper", "decode", "-i", inFile, "-o", outFile}, exitCorrupt},
+		{"BadHex", nil, []string{"decrypt", "--encoding", "hex", "-i", inFile, "-o", outFile, "-p", password}, exitCorrupt},
+		{"BadAlphabet", nil, []string{"decrypt", "-9", "--alphabet", "abc", "-p", password}, exitUsage},
+		{"AlphabetWithoutBase92", nil, []string{"decrypt", "--alphabet", "shell", "-p", password}, exitUsage},
+		{"WrongPassword", nil, []string{"decrypt", "-i", encFile, "-o", outFile, "-p", "wrong"}, exitWrongPassword},
+		{"Truncated", nil, []string{"decrypt", "-i", truncFile, "-o", outFile, "-p", password}, exitCorrupt},
+		{"BadBase64", nil, []string{"decrypt", "-6", "-i", inFile, "-o", outFile, "-p", password}, exitCorrupt},
+		{"BadBase92", nil, []string{"decrypt", "-9", "-i", inFile, "-o", outFile, "-p", password}, exitCorrupt},
+		{"MissingInput", nil, []string{"decrypt", "-i", filepath.Join(tempDir, "missing"), "-o", outFile, "-p", password}, exitIO},
+		{"Canceled", canceled, []string{"decrypt", "-i", encFile, "-o", outFile, "-p", password}, exitCanceled},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+			if tc.ctx != nil {
+				err = run(tc.ctx, tc.args)
+			} else {
+				_, err = capture(tc.args...)
+			}
+			if code := exitCode(err); code != tc.code {
+				t.Errorf("Expected exit code %d, got %d", tc.code, code)
+			}
+		})
+	}
+}
+
+func TestJSONResult(t *testing.T) {
+	tempDir := t.TempDir()
+	password := "YWJjMTIzIT8kKiYoKSctPUB+"
+	inFile := filepath.Join(tempDir, "input.txt")
+	encFile := filepath.Join(tempDir, "encrypted.bin")
+	plaintext := "Hello, World!"
+
+	if err := os.WriteFile(inFile, []byte(plaintext), 0644); err != nil {
+		t.Fatalf("Failed to write input file: %v", err)
+	}
+
+	out, err := capture("encrypt", "--json", "-i", inFile, "-o", encFile, "-p", password)
+	if err != nil {
+		t.Fatalf("Encryption failed: %v", err)
+	}
+	var r result
+	if err := json.Unmarshal([]byte(out), &r); err != nil {
+		t.Fatalf("Failed to parse result %q: %v", out, err)
+	}
+	if r.Operation != "encrypt" || r.Input != inFile || r.Output != encFile ||
+		r.Bytes != int64(len(plaintext)) || r.ExitCode != exitOK || r.Error != "" || r.Duration <= 0 {
+		t.Errorf("Unexpected result: %+v", r)
+	}
+
+	out, err = capture("-d", "--json", "-i", encFile, "-o", filepath.Join(tempDir, "output.txt"), "-p", "wrong")
+	if exitCode(err) != exitWrongPassword {
+		t.Fatalf("Expected exit code %d, got %v", exitWrongPassword, err)
+	}
+	r = result{}
+	if err := json.Unmarshal([]byte(out), &r); err != nil {
+		t.Fatalf("Failed to parse result %q: %v", out, err)
+	}
+	if r.Operation != "decrypt" || r.ExitCode != exitWrongPassword || r.ErrorCode != "wrong_password" ||
+		!strings.Contains(r.Error, "incorrect password") {
+		t.Errorf("Unexpected result: %+v", r)
+	}
+}
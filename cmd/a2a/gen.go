@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+
+	"github.com/presbrey/argon2aes"
+	"github.com/presbrey/argon2aes/pkg/base92"
+	"github.com/presbrey/argon2aes/pkg/words"
+	"github.com/spf13/pflag"
+)
+
+func genFlags(fs *pflag.FlagSet, f *flags) {
+	keygenFlags(fs, f)
+	wordsFlag(fs, f)
+}
+
+func keygenFlags(fs *pflag.FlagSet, f *flags) {
+	fs.StringVarP(&f.outputFile, "out", "o", "-", "Output file, created with mode 0600 (default: stdout)")
+	fs.BoolVarP(&f.url64, "url64", "u", false, "Print a key in URL-safe base64")
+	fs.BoolVarP(&f.base92, "base92", "9", false, "Print a key in base92")
+	fs.StringVar(&f.encoding, "encoding", "", "Print a key in the named encoding: base64, url64, base92 or words")
+}
+
+func wordsFlag(fs *pflag.FlagSet, f *flags) {
+	fs.IntVar(&f.words, "words", argon2aes.DefaultPassphraseWords, "Number of words in a generated passphrase")
+}
+
+// runGen prints a random passphrase or key.
+func runGen(ctx context.Context, f *flags, args []string) error {
+	if len(args) != 1 {
+		return usageErrorf("gen requires one argument: passphrase or key")
+	}
+
+	switch args[0] {
+	case "passphrase":
+		passphrase, err := argon2aes.GeneratePassphrase(f.words)
+		if err != nil {
+			return err
+		}
+		return writeSecret(f.outputFile, passphrase)
+	case "key":
+		return runKeygen(ctx, f, nil)
+	}
+	return usageErrorf("unknown secret type %q: must be passphrase or key", args[0])
+}
+
+// runKeygen prints a random key in a form accepted by --key.
+func runKeygen(ctx context.Context, f *flags, args []string) error {
+	if err := noArgs(args); err != nil {
+		return err
+	}
+	encoding := f.encoding
+	switch {
+	case f.url64 && (f.base92 || encoding != ""), f.base92 && encoding != "":
+		return usageErrorf("can only use one encoding option: url64, base92 or encoding")
+	case f.url64:
+		encoding = "url64"
+	case f.base92:
+		encoding = "base92"
+	}
+
+	key, err := argon2aes.GenerateKey()
+	if err != nil {
+		return err
+	}
+	var encoded string
+	switch encoding {
+	case "", "base64":
+		encoded = base64.StdEncoding.EncodeToString(key)
+	case "url64":
+		encoded = base64.URLEncoding.EncodeToString(key)
+	case "base92":
+		// --key reads the original base92 encoding, but only of keys it
+		// cannot mistake for base64.
+		for encoded = base92.DefaultEncoding.EncodeToString(key); onlyOf(encoded, base64Chars+url64Chars); {
+			if key, err = argon2aes.GenerateKey(); err != nil {
+				return err
+			}
+			encoded = base92.DefaultEncoding.EncodeToString(key)
+		}
+	case "words":
+		encoded = words.Encode(key)
+	default:
+		return usageErrorf("unknown key encoding %q: must be base64, url64, base92 or words", encoding)
+	}
+	return writeSecret(f.outputFile, encoded)
+}
+
+// writeSecret prints secret on a line of its own, to stdout or to a new
+// file that only the owner can read.
+func writeSecret(outputFile, secret string) error {
+	return writePrivate(outputFile, []byte(secret+"\n"))
+}
+
+// writePrivate writes data to stdout or to a new file that only the owner
+// can read.
+func writePrivate(outputFile string, data []byte) error {
+	if outputFile == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	file, err := os.OpenFile(outputFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
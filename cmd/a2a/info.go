@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/presbrey/argon2aes"
+	"github.com/spf13/pflag"
+)
+
+func infoFlags(fs *pflag.FlagSet, f *flags) {
+	encodingFlags(fs, f)
+	jsonFlag(fs, f)
+}
+
+func verifyFlags(fs *pflag.FlagSet, f *flags) {
+	passphraseFlags(fs, f)
+	encodingFlags(fs, f)
+	jobsFlag(fs, f)
+	jsonFlag(fs, f)
+}
+
+// runInfo prints the header of each file without decrypting it.
+func runInfo(ctx context.Context, f *flags, files []string) error {
+	if len(files) == 0 {
+		return usageErrorf("info requires at least one file")
+	}
+	if err := f.resolveEncoding(); err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		input, err := openInput(ctx, file)
+		if err != nil {
+			return err
+		}
+		decoder, err := f.newDecoder(input)
+		if err != nil {
+			input.Close()
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		i, err := argon2aes.Inspect(decoder)
+		input.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+
+		if f.json {
+			err = json.NewEncoder(os.Stdout).Encode(struct {
+				File string `json:"file"`
+				argon2aes.Info
+			}{file, i})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		fmt.Printf("File:        %s\n", file)
+		if i.Legacy {
+			fmt.Printf("Format:      legacy (no header)\n")
+		} else {
+			fmt.Printf("Format:      stream version %d\n", i.Version)
+		}
+		fmt.Printf("Cipher:      %s\n", i.Cipher)
+		fmt.Printf("KDF:         %s (time=%d, memory=%d KiB, threads=%d)\n", i.KDF, i.Time, i.Memory, i.Threads)
+		fmt.Printf("Salt:        %x\n", i.Salt)
+		if !i.Legacy {
+			fmt.Printf("Chunk size:  %d\n", i.ChunkSize)
+		}
+		fmt.Printf("Padding:     %s\n", i.Padding)
+		fmt.Printf("Compression: %s\n", i.Compression)
+		fmt.Printf("Payload:     %d bytes\n", i.PayloadSize)
+	}
+	return nil
+}
+
+// runVerify checks that each file decrypts, printing a line per file, and
+// fails if any of them does not.
+func runVerify(ctx context.Context, f *flags, files []string) error {
+	if len(files) == 0 {
+		return usageErrorf("verify requires at least one file")
+	}
+	if err := f.resolveEncoding(); err != nil {
+		return err
+	}
+	var passphrase []byte
+	if !f.useAgent() {
+		var err error
+		if passphrase, err = f.readPassphrase(false); err != nil {
+			return err
+		}
+	}
+
+	failed := 0
+	for _, file := range files {
+		err := verifyFile(ctx, f, file, passphrase)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			failed++
+		}
+
+		if f.json {
+			result := struct {
+				File  string `json:"file"`
+				OK    bool   `json:"ok"`
+				Error string `json:"error,omitempty"`
+			}{File: file, OK: err == nil}
+			if err != nil {
+				result.Error = err.Error()
+			}
+			if err := json.NewEncoder(os.Stdout).Encode(result); err != nil {
+				return err
+			}
+		} else if err != nil {
+			fmt.Printf("%s: FAILED: %v\n", file, err)
+		} else {
+			fmt.Printf("%s: OK\n", file)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files failed verification", failed, len(files))
+	}
+	return nil
+}
+
+func verifyFile(ctx context.Context, f *flags, file string, passphrase []byte) error {
+	input, err := openInput(ctx, file)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	decoder, err := f.newDecoder(input)
+	if err != nil {
+		return err
+	}
+	r, err := f.newReader(ctx, decoder, passphrase, argon2aes.WithJobs(f.jobs))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(io.Discard, r)
+	return err
+}
@@ -1,170 +1,565 @@
 package main
 
 import (
-	"encoding/base64"
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"os/signal"
 	"strings"
+	"time"
 
 	"github.com/presbrey/argon2aes"
 	"github.com/presbrey/argon2aes/pkg/base92"
+	"github.com/presbrey/argon2aes/pkg/codec"
 	"github.com/spf13/pflag"
-	"golang.org/x/term"
 )
 
-var (
-	passphrase, key,
+// flags holds the values of every command-line flag. Each command
+// registers only the flags it uses.
+type flags struct {
+	key, passphrase       string
+	passphraseEnv         string
+	passphraseFile        string
+	passphraseFD          int
+	newKey, newPassphrase string
 	inputFile, outputFile string
-	passphraseBytes                []byte
-	flagEncrypt, flagDecrypt       bool
-	useBase64, useBase92, useURL64 bool
-)
+	base64, base92, url64 bool
+	base92Legacy, armor   bool
+	encoding, alphabet    string
+	jobs                  int
+	pad, compress         string
+	minEntropy            float64
+	weakPassphrase        string
+	maxSize               int64
+	json                  bool
+	size                  int
+	words                 int
+	generate              bool
+	socket                string
+	ttl, idleTimeout      time.Duration
+	daemon                bool
+	argonTime             uint32
+	argonMemory           uint32
+	argonThreads          uint8
+	suffix                string
+	encrypt, decrypt      bool
 
-func init() {
-	pflag.StringVarP(&key, "key", "k", "", "Encryption key (base64 encoded)")
-	pflag.StringVarP(&passphrase, "passphrase", "p", "", "Encryption passphrase")
-	pflag.StringVarP(&inputFile, "in", "i", "-", "Input file (default: stdin)")
-	pflag.StringVarP(&outputFile, "out", "o", "-", "Output file (default: stdout)")
-	pflag.BoolVarP(&flagEncrypt, "encrypt", "e", false, "Encrypt mode")
-	pflag.BoolVarP(&flagDecrypt, "decrypt", "d", false, "Decrypt mode")
-	pflag.BoolVarP(&useBase64, "base64", "6", false, "Use standard base64 encoding for input/output")
-	pflag.BoolVarP(&useBase92, "base92", "9", false, "Use base92 encoding for input/output")
-	pflag.BoolVarP(&useURL64, "url64", "u", false, "Use URL-safe base64 encoding for input/output")
-	pflag.Parse()
+	// processed counts the plaintext bytes encrypted or decrypted.
+	processed int64
+
+	// config is the config file applied to the flags.
+	config *config
+
+	// codec is the text encoding of the ciphertext, or nil for none. It is
+	// set by resolveEncoding.
+	codec codec.Codec
+
+	// detect is set when no encoding was chosen, so that newDecoder
+	// recognizes it from the input.
+	detect bool
+}
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	flags   func(fs *pflag.FlagSet, f *flags)
+	run     func(ctx context.Context, f *flags, args []string) error
 }
 
+var commands = []command{
+	{"encrypt", "", "Encrypt a file", encryptFlags, runEncrypt},
+	{"decrypt", "", "Decrypt a file", decryptFlags, runDecrypt},
+	{"info", "<file>...", "Show how files were encrypted without decrypting them", infoFlags, runInfo},
+	{"verify", "<file>...", "Check that files decrypt without writing any plaintext", verifyFlags, runVerify},
+	{"rekey", "", "Re-encrypt a file with a new passphrase or key", rekeyFlags, runRekey},
+	{"gen", "passphrase|key", "Generate a random passphrase or key", genFlags, runGen},
+	{"keygen", "", "Generate a random key for use with --key (same as gen key)", keygenFlags, runKeygen},
+	{"bench", "", "Measure key derivation time and encryption throughput", benchFlags, runBench},
+	{"config", "show", "Show the settings in effect from the config file and flags", configFlags, runConfig},
+	{"paper", "encode|decode", "Lay out a key or other small file for printing, or read it back", paperFlags, runPaper},
+	{"agent", "[lock]", "Cache unlocked keys for other commands, or forget them with lock", agentFlags, runAgent},
+}
+
+// resultCommands are the commands that print a result with --json. The
+// others print their output as JSON and only report errors this way.
+var resultCommands = map[string]bool{"encrypt": true, "decrypt": true, "rekey": true}
+
 func main() {
-	if err := run(); err != nil {
-		log.Printf("Error: %v\n", err)
-		os.Exit(1)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	go func() {
+		// Restore the default behaviour so that a second interrupt exits
+		// immediately, even during key derivation.
+		<-ctx.Done()
+		stop()
+	}()
+
+	if err := run(ctx, os.Args[1:]); err != nil {
+		var reported *reportedError
+		if !errors.As(err, &reported) {
+			log.Printf("Error: %v\n", err)
+		}
+		os.Exit(exitCode(err))
 	}
 }
 
-func run() error {
-	var err error
-
-	if flagEncrypt == flagDecrypt {
-		pflag.Usage()
-		return fmt.Errorf("must specify either encrypt or decrypt mode")
+// run parses args, which exclude the program name, and runs the selected
+// command.
+func run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		usage()
+		return usageErrorf("no command given")
 	}
 
-	if (useBase64 && useBase92) || (useBase64 && useURL64) || (useBase92 && useURL64) {
-		return fmt.Errorf("can only use one encoding option: base64, url64, or base92")
+	name := args[0]
+	switch {
+	case name == "help" || name == "-h" || name == "--help":
+		if len(args) > 1 {
+			return run(ctx, []string{args[1], "--help"})
+		}
+		usage()
+		return nil
+	case strings.HasPrefix(name, "-"):
+		return runLegacy(ctx, args)
 	}
 
-	if key != "" {
-		var encoding *base64.Encoding
-		if strings.ContainsAny(key, "-_") {
-			encoding = base64.URLEncoding
-		} else {
-			encoding = base64.StdEncoding
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
 		}
-		passphraseBytes, err = encoding.DecodeString(key)
-		if err != nil {
-			return fmt.Errorf("invalid key. Must be base64 encoded")
+
+		f := &flags{}
+		fs := pflag.NewFlagSet("a2a "+cmd.name, pflag.ContinueOnError)
+		cmd.flags(fs, f)
+		fs.Usage = func() {
+			fmt.Fprintf(os.Stderr, "Usage of a2a %s:\n  a2a %s [flags] %s\n\n%s.\n\nFlags:\n",
+				cmd.name, cmd.name, cmd.args, cmd.summary)
+			fs.PrintDefaults()
 		}
-	} else if passphrase != "" {
-		passphraseBytes = []byte(passphrase)
-	} else {
-		fmt.Print("Enter passphrase: ")
-		passphraseBytes, err = term.ReadPassword(int(os.Stdin.Fd()))
-		if err != nil {
-			return fmt.Errorf("error reading passphrase: %v", err)
+		if err := fs.Parse(args[1:]); err != nil {
+			if err == pflag.ErrHelp {
+				return nil
+			}
+			return &usageError{err}
+		}
+
+		start := time.Now()
+		err := f.applyConfig(fs)
+		if err == nil {
+			err = cmd.run(ctx, f, fs.Args())
 		}
-		fmt.Println() // Print a newline after the password input
+		if f.json && (err != nil || resultCommands[cmd.name]) {
+			return f.report(cmd.name, start, err)
+		}
+		return err
 	}
 
-	if len(passphraseBytes) == 0 {
-		return fmt.Errorf("passphrase cannot be empty")
+	usage()
+	return usageErrorf("unknown command %q", name)
+}
+
+// runLegacy handles the original flag-only invocation, where -e or -d
+// selects the encrypt or decrypt command.
+func runLegacy(ctx context.Context, args []string) error {
+	f := &flags{}
+	fs := pflag.NewFlagSet("a2a", pflag.ContinueOnError)
+	fs.BoolVarP(&f.encrypt, "encrypt", "e", false, "Encrypt mode")
+	fs.BoolVarP(&f.decrypt, "decrypt", "d", false, "Decrypt mode")
+	encryptFlags(fs, f)
+	fs.Int64Var(&f.maxSize, "max-size", 0, "Fail when decrypted output exceeds this many bytes (default: 1 GiB for compressed files, otherwise no limit)")
+	fs.Usage = usage
+	if err := fs.Parse(args); err != nil {
+		if err == pflag.ErrHelp {
+			return nil
+		}
+		return &usageError{err}
 	}
 
-	if flagEncrypt {
-		err = encrypt(inputFile, outputFile, passphraseBytes)
-	} else {
-		err = decrypt(inputFile, outputFile, passphraseBytes)
+	if f.encrypt == f.decrypt {
+		usage()
+		return usageErrorf("must specify either encrypt or decrypt mode")
 	}
 
+	operation, runOperation := "encrypt", runEncrypt
+	if f.decrypt {
+		operation, runOperation = "decrypt", runDecrypt
+	}
+	start := time.Now()
+	err := f.applyConfig(fs)
+	if err == nil {
+		err = runOperation(ctx, f, fs.Args())
+	}
+	if f.json {
+		return f.report(operation, start, err)
+	}
 	return err
 }
 
-func encrypt(inputFile, outputFile string, passphrase []byte) error {
-	input, err := readInput(inputFile)
-	if err != nil {
-		return err
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage of a2a:\n  a2a <command> [flags]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s  %s\n", cmd.name, cmd.summary)
 	}
+	fmt.Fprintf(os.Stderr, "\nThe -e/--encrypt and -d/--decrypt flags may be used in place of the encrypt\n"+
+		"and decrypt commands. Run 'a2a help <command>' for the flags of a command.\n")
+}
 
-	encrypted, err := argon2aes.Encrypt(input, passphrase)
-	if err != nil {
-		return err
+func ioFlags(fs *pflag.FlagSet, f *flags) {
+	fs.StringVarP(&f.inputFile, "in", "i", "-", "Input file (default: stdin)")
+	fs.StringVarP(&f.outputFile, "out", "o", "", "Output file (default: stdout, or the input file with --suffix added or removed)")
+}
+
+func suffixFlag(fs *pflag.FlagSet, f *flags) {
+	fs.StringVar(&f.suffix, "suffix", "", "Name the output after the input file, adding this suffix when encrypting and removing it when decrypting")
+}
+
+// resolveOutput picks the output file when -o is not given: stdout, or
+// with --suffix a file named after the input.
+func (f *flags) resolveOutput(decrypt bool) error {
+	if f.outputFile != "" {
+		return nil
+	}
+	f.outputFile = "-"
+	if f.suffix == "" || f.inputFile == "-" {
+		return nil
+	}
+	if !decrypt {
+		f.outputFile = f.inputFile + f.suffix
+		return nil
 	}
+	if !strings.HasSuffix(f.inputFile, f.suffix) || len(f.inputFile) == len(f.suffix) {
+		return fmt.Errorf("cannot name the output: %s does not end in %s; use -o", f.inputFile, f.suffix)
+	}
+	f.outputFile = strings.TrimSuffix(f.inputFile, f.suffix)
+	return nil
+}
 
-	return writeOutput(outputFile, encrypted)
+func encodingFlags(fs *pflag.FlagSet, f *flags) {
+	fs.BoolVarP(&f.base64, "base64", "6", false, "Use standard base64 encoding for the ciphertext")
+	fs.BoolVarP(&f.base92, "base92", "9", false, "Use base92 encoding for the ciphertext")
+	fs.BoolVar(&f.base92Legacy, "base92-legacy", false, "Use the slower base92 encoding of earlier versions, for files written with -9 before it changed")
+	fs.BoolVarP(&f.url64, "url64", "u", false, "Use URL-safe base64 encoding for the ciphertext")
+	fs.BoolVar(&f.armor, "armor", false, "Wrap the ciphertext in ASCII armor with a checksum")
+	fs.StringVar(&f.encoding, "encoding", "", "Text encoding of the ciphertext: none, "+strings.Join(codec.Names(), ", "))
+	fs.StringVar(&f.alphabet, "alphabet", "", "Alphabet for base92: std, shell, json or 92 characters (default std)")
 }
 
-func decrypt(inputFile, outputFile string, passphrase []byte) error {
-	input, err := readInput(inputFile)
-	if err != nil {
-		return err
+func jobsFlag(fs *pflag.FlagSet, f *flags) {
+	fs.IntVarP(&f.jobs, "jobs", "j", 0, "Number of chunks to encrypt or decrypt in parallel (default: one per CPU)")
+}
+
+func jsonFlag(fs *pflag.FlagSet, f *flags) {
+	fs.BoolVar(&f.json, "json", false, "Print results as JSON")
+}
+
+func resultFlag(fs *pflag.FlagSet, f *flags) {
+	fs.BoolVar(&f.json, "json", false, "Print a JSON summary of the result to stderr")
+}
+
+// encodingFlagNames are the flags that select the ciphertext encoding.
+// Each shorthand is named after the codec it selects.
+var encodingFlagNames = []string{"encoding", "base64", "url64", "base92", "base92-legacy", "armor"}
+
+// resolveEncoding sets f.codec from --encoding or its shorthands, rejecting
+// more than one.
+func (f *flags) resolveEncoding() error {
+	name := f.encoding
+	for i, set := range []bool{f.base64, f.url64, f.base92, f.base92Legacy, f.armor} {
+		if !set {
+			continue
+		}
+		if name != "" {
+			return usageErrorf("can only use one encoding option: encoding, base64, url64, base92, base92-legacy or armor")
+		}
+		name = encodingFlagNames[i+1]
 	}
 
-	decrypted, err := argon2aes.Decrypt(input, passphrase)
-	if err != nil {
-		return err
+	f.codec, f.detect = nil, name == "" && f.alphabet == ""
+	if f.alphabet != "" {
+		if name != "base92" && name != "base92-legacy" {
+			return usageErrorf("--alphabet requires --base92 or --base92-legacy")
+		}
+		enc, ok := base92Alphabets[f.alphabet]
+		if !ok {
+			var err error
+			if enc, err = base92.NewEncoding(f.alphabet); err != nil {
+				return usageErrorf("invalid alphabet: %v", err)
+			}
+		}
+		if name == "base92-legacy" {
+			enc = enc.Legacy()
+		}
+		f.codec = codec.Base92(name, enc)
+		return nil
+	}
+	if name == "" || name == "none" {
+		return nil
+	}
+	if f.codec = codec.Lookup(name); f.codec == nil {
+		return usageErrorf("unknown encoding %q: must be none, %s", name, strings.Join(codec.Names(), ", "))
+	}
+	return nil
+}
+
+// openInput opens the named file, or stdin for "-". Reads of stdin return
+// ctx.Err() once ctx is done, rather than waiting for more input.
+func openInput(ctx context.Context, inputFile string) (io.ReadCloser, error) {
+	if inputFile == "-" {
+		return io.NopCloser(&ctxReader{ctx: ctx, r: os.Stdin}), nil
 	}
+	return os.Open(inputFile)
+}
 
-	return writeOutput(outputFile, decrypted)
+// ctxReader reads r in the background, so that Read returns ctx.Err() as
+// soon as ctx is done even while r blocks, as a pipe or terminal does
+// until more input arrives. A read in progress then is abandoned, along
+// with its data.
+type ctxReader struct {
+	ctx     context.Context
+	r       io.Reader
+	buf     []byte
+	pending chan readResult // the read in progress, if any
 }
 
-func readInput(inputFile string) ([]byte, error) {
-	var input []byte
-	var err error
+type readResult struct {
+	n   int
+	err error
+}
 
-	if inputFile == "-" {
-		input, err = io.ReadAll(os.Stdin)
-	} else {
-		input, err = os.ReadFile(inputFile)
+func (c *ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if c.pending == nil {
+		// The read gets a buffer of its own, which it may still be
+		// writing to after Read has returned.
+		if cap(c.buf) < len(p) {
+			c.buf = make([]byte, len(p))
+		}
+		buf, pending := c.buf[:len(p)], make(chan readResult, 1)
+		go func() {
+			n, err := c.r.Read(buf)
+			pending <- readResult{n, err}
+		}()
+		c.pending = pending
+	}
+	select {
+	case <-c.ctx.Done():
+		return 0, c.ctx.Err()
+	case res := <-c.pending:
+		c.pending = nil
+		return copy(p, c.buf[:res.n]), res.err
+	}
+}
+
+func createOutput(outputFile string) (io.WriteCloser, error) {
+	if outputFile == "-" {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+	return os.OpenFile(outputFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+}
+
+// removeOnError deletes a partially written output file when *err is set.
+func removeOnError(outputFile string, err *error) {
+	if *err != nil && outputFile != "-" {
+		os.Remove(outputFile)
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// newEncoder wraps the ciphertext output in the selected text encoding.
+func (f *flags) newEncoder(w io.Writer) io.WriteCloser {
+	if f.codec == nil {
+		return nopWriteCloser{w}
 	}
+	return f.codec.NewEncoder(w)
+}
 
+// newDecoder undoes the text encoding of the ciphertext input. Without an
+// encoding flag it detects the encoding, and keeps it in f.codec so that
+// rekey writes the same one.
+func (f *flags) newDecoder(r io.Reader) (io.Reader, error) {
+	switch {
+	case f.detect:
+		br := bufio.NewReaderSize(r, armorSearchLength)
+		start, err := br.Peek(armorSearchLength)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if argon2aes.IsArmored(start) {
+			f.codec, r = armorCodec, br
+		} else if f.codec, r, err = codec.Detect(br, argon2aes.Magic()); err != nil {
+			return nil, err
+		}
+		if f.codec == nil && !bytes.HasPrefix(start, argon2aes.Magic()) {
+			return f.detectLegacy(r)
+		}
+	case f.codec == codec.Lookup("base92"):
+		// Before the stream format, -9 wrote the legacy base92 encoding.
+		br := bufio.NewReaderSize(r, base92PeekLength)
+		start, err := br.Peek(base92PeekLength)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		decoded := make([]byte, len(argon2aes.Magic()))
+		_, err = io.ReadFull(f.codec.NewDecoder(bytes.NewReader(start)), decoded)
+		if err != nil || !bytes.Equal(decoded, argon2aes.Magic()) {
+			f.codec = codec.Lookup("base92-legacy")
+		}
+		r = br
+	}
+	if f.codec == nil {
+		return r, nil
+	}
+	return f.codec.NewDecoder(r), nil
+}
+
+// base92PeekLength is how much of base92 input newDecoder decodes to find
+// the signature of the stream format: its first group, with room for line
+// breaks.
+const base92PeekLength = 64
+
+// legacyMinLength is the length of the shortest ciphertext of the original
+// format, which has no header: a salt, a nonce and an authentication tag.
+const legacyMinLength = 32 + 12 + 16
+
+// The characters of the encodings detectLegacy tells apart, with the
+// padding of those that have it.
+const (
+	hexChars    = "0123456789abcdefABCDEF"
+	base32Chars = "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567="
+	base58Chars = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+	alnumChars  = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	base64Chars = alnumChars + "+/="
+	url64Chars  = alnumChars + "-_="
+)
+
+// detectLegacy reads input in which codec.Detect found no signature: a
+// file of a version before the stream format, in raw binary, base64 or
+// legacy base92, or a stream in base58 or legacy base92, which encode the
+// whole input as one number. Other input is reported as corrupt rather
+// than as a wrong password after a key is derived for it.
+func (f *flags) detectLegacy(r io.Reader) (io.Reader, error) {
+	data, err := io.ReadAll(r)
 	if err != nil {
 		return nil, err
 	}
+	if !isText(data) {
+		return bytes.NewReader(data), nil
+	}
 
-	if flagDecrypt {
-		if useBase64 {
-			return base64.StdEncoding.DecodeString(string(input))
-		} else if useURL64 {
-			return base64.URLEncoding.DecodeString(string(input))
-		} else if useBase92 {
-			return base92.DefaultEncoding.DecodeString(string(input))
+	symbols := strings.Join(strings.Fields(string(data)), "")
+	var candidates []string
+	switch {
+	case mostlyOf(symbols, hexChars) || mostlyOf(symbols, base32Chars):
+		// Text of an encoding that Detect recognizes, with a damaged
+		// signature.
+	case onlyOf(symbols, base58Chars):
+		candidates = []string{"base58", "base64"}
+	case onlyOf(symbols, base64Chars):
+		candidates = []string{"base64"}
+	case onlyOf(symbols, url64Chars):
+		candidates = []string{"url64"}
+	case !mostlyOf(symbols, base64Chars+url64Chars):
+		// Base92 uses the characters that base64 does not for more than
+		// a quarter of its text, and base64 with a few damaged
+		// characters does not.
+		candidates = []string{"base92-legacy"}
+	}
+	for _, name := range candidates {
+		c := codec.Lookup(name)
+		decoded, err := codec.DecodeString(c, string(data))
+		if err != nil {
+			continue
+		}
+		if bytes.HasPrefix(decoded, argon2aes.Magic()) || (name != "base58" && len(decoded) >= legacyMinLength) {
+			f.codec = c
+			return bytes.NewReader(decoded), nil
 		}
 	}
+	return nil, fmt.Errorf("%w: unrecognised encoding; use --encoding", codec.ErrCorrupt)
+}
 
-	return input, nil
+// isText reports whether data is printable ASCII and whitespace, which the
+// raw ciphertext of the original format almost never is.
+func isText(data []byte) bool {
+	for _, c := range data {
+		if (c < ' ' || c > '~') && !strings.ContainsRune("\t\n\v\f\r", rune(c)) {
+			return false
+		}
+	}
+	return true
 }
 
-func writeOutput(outputFile string, data []byte) error {
-	var output []byte
+// onlyOf reports whether every character of s is in chars.
+func onlyOf(s, chars string) bool {
+	return countNotOf(s, chars) == 0
+}
 
-	if flagEncrypt {
-		if useBase64 {
-			output = []byte(base64.RawStdEncoding.EncodeToString(data))
-		} else if useURL64 {
-			output = []byte(base64.RawURLEncoding.EncodeToString(data))
-		} else if useBase92 {
-			output = []byte(base92.DefaultEncoding.EncodeToString(data))
-		} else {
-			output = data
+// mostlyOf reports whether all but a sixteenth of the characters of s are
+// in chars, as in text of that alphabet with a few damaged characters.
+func mostlyOf(s, chars string) bool {
+	return 16*countNotOf(s, chars) < len(s)
+}
+
+// countNotOf returns the number of characters of s that are not in chars.
+func countNotOf(s, chars string) int {
+	n := 0
+	for _, c := range s {
+		if !strings.ContainsRune(chars, c) {
+			n++
 		}
-	} else {
-		output = data
 	}
+	return n
+}
 
-	if outputFile == "-" {
-		_, err := os.Stdout.Write(output)
-		return err
+// armorSearchLength is how far into the input newDecoder looks for the
+// start of armor, which may follow a message or email headers.
+const armorSearchLength = 16 << 10
+
+// armorCodec is the ASCII armor of argon2aes, which wraps base64.
+var armorCodec = codec.New("armor",
+	func(w io.Writer) io.WriteCloser {
+		aw, _ := argon2aes.NewArmorWriter(w, nil) // no headers to reject
+		return aw
+	},
+	func(r io.Reader) io.Reader { return &dearmorReader{src: r} })
+
+func init() {
+	codec.Register(armorCodec)
+}
+
+// dearmorReader reads the armored message of src, which it looks for on
+// the first Read.
+type dearmorReader struct {
+	src io.Reader
+	r   io.Reader
+}
+
+func (d *dearmorReader) Read(p []byte) (int, error) {
+	if d.r == nil {
+		r, _, err := argon2aes.NewArmorReader(d.src)
+		if err != nil {
+			return 0, err
+		}
+		d.r = r
 	}
+	return d.r.Read(p)
+}
 
-	return os.WriteFile(outputFile, output, 0644)
+// base92Alphabets are the alphabets --alphabet accepts by name.
+var base92Alphabets = map[string]*base92.Encoding{
+	"std":   base92.StdEncoding,
+	"shell": base92.ShellEncoding,
+	"json":  base92.JSONEncoding,
 }
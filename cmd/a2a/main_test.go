@@ -2,15 +2,49 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/presbrey/argon2aes"
 	"github.com/presbrey/argon2aes/pkg/base92"
+	"github.com/presbrey/argon2aes/pkg/codec"
+	"github.com/presbrey/argon2aes/pkg/paper"
+	"github.com/presbrey/argon2aes/pkg/words"
 )
 
+// capture runs a2a with args, returning what it printed to stdout and
+// stderr.
+func capture(args ...string) (string, error) {
+	oldStdout := os.Stdout
+	oldStderr := os.Stderr
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	os.Stderr = w
+
+	done := make(chan []byte)
+	go func() {
+		out, _ := io.ReadAll(r)
+		done <- out
+	}()
+
+	err := run(context.Background(), args)
+
+	w.Close()
+	out := <-done
+	os.Stdout = oldStdout
+	os.Stderr = oldStderr
+	return string(out), err
+}
+
 func TestMain(t *testing.T) {
 	// Create a temporary directory for test files
 	tempDir, err := os.MkdirTemp("", "a2a_test")
@@ -21,7 +55,7 @@ func TestMain(t *testing.T) {
 
 	// Test data
 	plaintext := []byte("Hello, World!")
-	password := []byte("YWJjMTIzIT8kKiYoKSctPUB+")
+	password := "YWJjMTIzIT8kKiYoKSctPUB+"
 
 	// Test encryption
 	t.Run("Encrypt", func(t *testing.T) {
@@ -33,30 +67,11 @@ func TestMain(t *testing.T) {
 			t.Fatalf("Failed to write input file: %v", err)
 		}
 
-		// Redirect stdout and stderr
-		oldStdout := os.Stdout
-		oldStderr := os.Stderr
-		_, w, _ := os.Pipe()
-		os.Stdout = w
-		os.Stderr = w
-
-		// Set command-line arguments
-		flagEncrypt = true
-		inputFile = inFile
-		outputFile = outFile
-		passphrase = string(password)
-
-		// Run the command
-		err = run()
+		_, err = capture("-e", "-i", inFile, "-o", outFile, "-p", password)
 		if err != nil {
 			t.Fatalf("Failed to run command: %v", err)
 		}
 
-		// Restore stdout and stderr
-		w.Close()
-		os.Stdout = oldStdout
-		os.Stderr = oldStderr
-
 		// Check if the output file exists
 		if _, err := os.Stat(outFile); os.IsNotExist(err) {
 			t.Errorf("Output file was not created")
@@ -68,31 +83,11 @@ func TestMain(t *testing.T) {
 		inFile := filepath.Join(tempDir, "encrypted.bin")
 		outFile := filepath.Join(tempDir, "decrypted.txt")
 
-		// Redirect stdout and stderr
-		oldStdout := os.Stdout
-		oldStderr := os.Stderr
-		_, w, _ := os.Pipe()
-		os.Stdout = w
-		os.Stderr = w
-
-		// Set command-line arguments
-		flagEncrypt = false
-		flagDecrypt = true
-		inputFile = inFile
-		outputFile = outFile
-		passphrase = string(password)
-
-		// Run the command
-		err = run()
+		_, err := capture("-d", "-i", inFile, "-o", outFile, "-p", password)
 		if err != nil {
 			t.Fatalf("Failed to run command: %v", err)
 		}
 
-		// Restore stdout and stderr
-		w.Close()
-		os.Stdout = oldStdout
-		os.Stderr = oldStderr
-
 		// Read the decrypted file
 		decrypted, err := os.ReadFile(outFile)
 		if err != nil {
@@ -107,34 +102,49 @@ func TestMain(t *testing.T) {
 
 	// Test invalid arguments
 	t.Run("InvalidArgs", func(t *testing.T) {
-		// Redirect stdout and stderr
-		oldStdout := os.Stdout
-		oldStderr := os.Stderr
-		r, w, _ := os.Pipe()
-		os.Stdout = w
-		os.Stderr = w
-
-		// Set invalid command-line arguments (both encrypt and decrypt)
-		flagEncrypt = true
-		flagDecrypt = true
-
-		// Run the command
-		err := run()
-
-		// Restore stdout and stderr
-		w.Close()
-		out, _ := io.ReadAll(r)
-		os.Stdout = oldStdout
-		os.Stderr = oldStderr
+		testCases := []struct {
+			name string
+			args []string
+		}{
+			{"EncryptAndDecrypt", []string{"-e", "-d"}},
+			{"NoMode", []string{"-p", password}},
+			{"NoCommand", nil},
+			{"UnknownCommand", []string{"frobnicate"}},
+		}
 
-		// Check if an error was returned
-		if err == nil {
-			t.Errorf("Expected an error for invalid arguments, but got none")
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				out, err := capture(tc.args...)
+
+				// Check if an error was returned
+				if err == nil {
+					t.Errorf("Expected an error for invalid arguments, but got none")
+				}
+
+				// Check if usage information was printed
+				if !strings.Contains(out, "Usage of") {
+					t.Errorf("Usage information was not printed for invalid arguments")
+				}
+			})
 		}
 
-		// Check if usage information was printed
-		if !bytes.Contains(out, []byte("Usage of")) {
-			t.Errorf("Usage information was not printed for invalid arguments")
+		// Flags belong to the commands that use them
+		if _, err := capture("info", "--pad", "padme", "file"); err == nil {
+			t.Errorf("Expected an error for a flag of another command, but got none")
+		}
+		if _, err := capture("encrypt", "extra"); err == nil {
+			t.Errorf("Expected an error for an unexpected argument, but got none")
+		}
+	})
+
+	// Test help
+	t.Run("Help", func(t *testing.T) {
+		out, err := capture("help", "rekey")
+		if err != nil {
+			t.Errorf("Help failed: %v", err)
+		}
+		if !strings.Contains(out, "Usage of a2a rekey") || !strings.Contains(out, "--new-passphrase") {
+			t.Errorf("Unexpected help output: %q", out)
 		}
 	})
 
@@ -153,27 +163,13 @@ func TestMain(t *testing.T) {
 		base64Key := "YWJjMTIzIT8kKiYoKSctPUB+"
 
 		// Encrypt with base64 key
-		flagEncrypt = true
-		flagDecrypt = false
-		inputFile = inFile
-		outputFile = outFile
-		key = base64Key
-		passphrase = ""
-
-		err = run()
+		err = run(context.Background(), []string{"-e", "-i", inFile, "-o", outFile, "-k", base64Key})
 		if err != nil {
 			t.Fatalf("Failed to run encryption with base64 key: %v", err)
 		}
 
 		// Decrypt with base64 key
-		flagEncrypt = false
-		flagDecrypt = true
-		inputFile = outFile
-		outputFile = decryptedFile
-		key = base64Key
-		passphrase = ""
-
-		err = run()
+		err = run(context.Background(), []string{"-d", "-i", outFile, "-o", decryptedFile, "-k", base64Key})
 		if err != nil {
 			t.Fatalf("Failed to run decryption with base64 key: %v", err)
 		}
@@ -205,27 +201,13 @@ func TestMain(t *testing.T) {
 		urlSafeKey := "YWJjMTIzIT8kKiYoKSctPUB-"
 
 		// Encrypt with URL-safe base64 key
-		flagEncrypt = true
-		flagDecrypt = false
-		inputFile = inFile
-		outputFile = outFile
-		key = urlSafeKey
-		passphrase = ""
-
-		err = run()
+		err = run(context.Background(), []string{"encrypt", "-i", inFile, "-o", outFile, "-k", urlSafeKey})
 		if err != nil {
 			t.Fatalf("Failed to run encryption with URL-safe base64 key: %v", err)
 		}
 
 		// Decrypt with URL-safe base64 key
-		flagEncrypt = false
-		flagDecrypt = true
-		inputFile = outFile
-		outputFile = decryptedFile
-		key = urlSafeKey
-		passphrase = ""
-
-		err = run()
+		err = run(context.Background(), []string{"decrypt", "-i", outFile, "-o", decryptedFile, "-k", urlSafeKey})
 		if err != nil {
 			t.Fatalf("Failed to run decryption with URL-safe base64 key: %v", err)
 		}
@@ -256,28 +238,128 @@ func TestMain(t *testing.T) {
 		invalidKey := "this is not a valid base64 key"
 
 		// Try to encrypt with invalid key
-		flagEncrypt = true
-		flagDecrypt = false
-		inputFile = inFile
-		outputFile = outFile
-		key = invalidKey
-		passphrase = ""
-
-		err = run()
+		err = run(context.Background(), []string{"encrypt", "-i", inFile, "-o", outFile, "-k", invalidKey})
 		if err == nil {
 			t.Errorf("Expected an error when encrypting with invalid key, but got none")
 		}
 
 		// Try to decrypt with invalid key
-		flagEncrypt = false
-		flagDecrypt = true
-		inputFile = outFile
-		outputFile = filepath.Join(tempDir, "decrypted_invalid.txt")
-
-		err = run()
+		err = run(context.Background(), []string{"decrypt", "-i", outFile,
+			"-o", filepath.Join(tempDir, "decrypted_invalid.txt"), "-k", invalidKey})
 		if err == nil {
 			t.Errorf("Expected an error when decrypting with invalid key, but got none")
 		}
+
+		// A truncated base64 key is not read as base92
+		key := base64.StdEncoding.EncodeToString(make([]byte, 32))
+		for _, truncated := range []string{key[:len(key)-1], key[:len(key)-2]} {
+			err = run(context.Background(), []string{"encrypt", "-i", inFile, "-o", outFile, "-k", truncated})
+			if err == nil {
+				t.Errorf("Expected an error when encrypting with truncated key %q, but got none", truncated)
+			}
+		}
+	})
+
+	// Test passphrase sources
+	t.Run("PassphraseSources", func(t *testing.T) {
+		inFile := filepath.Join(tempDir, "input_sources.txt")
+		outFile := filepath.Join(tempDir, "encrypted_sources.bin")
+		decryptedFile := filepath.Join(tempDir, "decrypted_sources.txt")
+		passphraseFile := filepath.Join(tempDir, "passphrase.txt")
+
+		err := os.WriteFile(inFile, plaintext, 0644)
+		if err != nil {
+			t.Fatalf("Failed to write input file: %v", err)
+		}
+		err = os.WriteFile(passphraseFile, []byte(password+"\r\n"), 0600)
+		if err != nil {
+			t.Fatalf("Failed to write passphrase file: %v", err)
+		}
+		t.Setenv("A2A_TEST_PASSPHRASE", password)
+
+		// Encrypt with the passphrase from the environment
+		err = run(context.Background(), []string{"encrypt", "-i", inFile, "-o", outFile, "--passphrase-env", "A2A_TEST_PASSPHRASE"})
+		if err != nil {
+			t.Fatalf("Failed to run encryption with --passphrase-env: %v", err)
+		}
+
+		// Decrypt with the same passphrase from a file
+		err = run(context.Background(), []string{"decrypt", "-i", outFile, "-o", decryptedFile, "--passphrase-file", passphraseFile})
+		if err != nil {
+			t.Fatalf("Failed to run decryption with --passphrase-file: %v", err)
+		}
+		decrypted, err := os.ReadFile(decryptedFile)
+		if err != nil {
+			t.Fatalf("Failed to read decrypted file: %v", err)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Errorf("Decrypted content does not match original. Got %s, want %s", decrypted, plaintext)
+		}
+
+		testCases := []struct {
+			name string
+			args []string
+		}{
+			{"EnvAndPassphrase", []string{"--passphrase-env", "A2A_TEST_PASSPHRASE", "-p", password}},
+			{"FileAndKey", []string{"--passphrase-file", passphraseFile, "-k", password}},
+			{"FileAndFD", []string{"--passphrase-file", passphraseFile, "--passphrase-fd", "3"}},
+			{"UnsetEnv", []string{"--passphrase-env", "A2A_TEST_UNSET"}},
+			{"MissingFile", []string{"--passphrase-file", filepath.Join(tempDir, "missing.txt")}},
+			{"StdinFD", []string{"--passphrase-fd", "0", "-i", "-"}},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				args := append([]string{"decrypt", "-i", outFile, "-o", decryptedFile}, tc.args...)
+				if err := run(context.Background(), args); err == nil {
+					t.Errorf("Expected an error, but got none")
+				}
+			})
+		}
+	})
+
+	// Test passphrase strength policy
+	t.Run("WeakPassphrase", func(t *testing.T) {
+		inFile := filepath.Join(tempDir, "input_weak.txt")
+		outFile := filepath.Join(tempDir, "encrypted_weak.bin")
+
+		err := os.WriteFile(inFile, plaintext, 0644)
+		if err != nil {
+			t.Fatalf("Failed to write input file: %v", err)
+		}
+
+		// Refused passphrases leave no output
+		err = run(context.Background(), []string{"encrypt", "-i", inFile, "-o", outFile, "-p", "P@ssw0rd", "--weak-passphrase", "refuse"})
+		if err == nil {
+			t.Errorf("Expected an error when encrypting with a weak passphrase, but got none")
+		}
+		if _, err := os.Stat(outFile); !os.IsNotExist(err) {
+			t.Errorf("Output file was created despite the weak passphrase")
+		}
+
+		// By default a weak passphrase only prints a warning
+		out, err := capture("encrypt", "-i", inFile, "-o", outFile, "-p", "P@ssw0rd")
+		if err != nil {
+			t.Fatalf("Failed to run encryption with a weak passphrase: %v", err)
+		}
+		if !strings.Contains(out, "Warning: passphrase is weak") || !strings.Contains(out, "common password") {
+			t.Errorf("Unexpected warning for a weak passphrase: %q", out)
+		}
+
+		// The minimum is configurable
+		err = run(context.Background(), []string{"encrypt", "-i", inFile, "-o", outFile, "-p", "horizontal", "--weak-passphrase", "refuse", "--min-entropy", "5"})
+		if err != nil {
+			t.Errorf("Failed to run encryption with a lower minimum: %v", err)
+		}
+
+		// Decryption is never refused
+		err = run(context.Background(), []string{"decrypt", "-i", outFile, "-o", filepath.Join(tempDir, "decrypted_weak.txt"), "-p", "horizontal"})
+		if err != nil {
+			t.Errorf("Failed to run decryption with a weak passphrase: %v", err)
+		}
+
+		if _, err := capture("encrypt", "-i", inFile, "-o", outFile, "-p", password, "--weak-passphrase", "ignore"); err == nil {
+			t.Errorf("Expected an error for an invalid policy, but got none")
+		}
 	})
 
 	// Test base64 encoding
@@ -293,29 +375,13 @@ func TestMain(t *testing.T) {
 		}
 
 		// Encrypt with base64 input
-		flagEncrypt = true
-		flagDecrypt = false
-		inputFile = inFile
-		outputFile = outFile
-		key = string(password)
-		useBase64 = true
-		useBase92 = false
-
-		err = run()
+		err = run(context.Background(), []string{"-e", "-i", inFile, "-o", outFile, "-k", password, "-6"})
 		if err != nil {
 			t.Fatalf("Failed to run encryption with base64 input: %v", err)
 		}
 
 		// Decrypt with base64 output
-		flagEncrypt = false
-		flagDecrypt = true
-		inputFile = outFile
-		outputFile = decryptedFile
-		passphrase = string(password)
-		useBase64 = true
-		useBase92 = false
-
-		err = run()
+		err = run(context.Background(), []string{"-d", "-i", outFile, "-o", decryptedFile, "-k", password, "-6"})
 		if err != nil {
 			t.Fatalf("Failed to run decryption with base64 output: %v", err)
 		}
@@ -351,29 +417,13 @@ func TestMain(t *testing.T) {
 		}
 
 		// Encrypt with base92 input
-		flagEncrypt = true
-		flagDecrypt = false
-		inputFile = inFile
-		outputFile = outFile
-		passphrase = string(password)
-		useBase64 = false
-		useBase92 = true
-
-		err = run()
+		err = run(context.Background(), []string{"-e", "-i", inFile, "-o", outFile, "-p", password, "-9"})
 		if err != nil {
 			t.Fatalf("Failed to run encryption with base92 input: %v", err)
 		}
 
 		// Decrypt with base92 output
-		flagEncrypt = false
-		flagDecrypt = true
-		inputFile = outFile
-		outputFile = decryptedFile
-		passphrase = string(password)
-		useBase64 = false
-		useBase92 = true
-
-		err = run()
+		err = run(context.Background(), []string{"-d", "-i", outFile, "-o", decryptedFile, "-p", password, "-9"})
 		if err != nil {
 			t.Fatalf("Failed to run decryption with base92 output: %v", err)
 		}
@@ -395,4 +445,957 @@ func TestMain(t *testing.T) {
 			t.Errorf("Decrypted content does not match original. Got %s, want %s", decodedDecrypted, plaintext)
 		}
 	})
+	// Files written with -9 by earlier versions use the legacy base92
+	t.Run("Base92Legacy", func(t *testing.T) {
+		inFile := filepath.Join(tempDir, "input_base92_legacy.txt")
+		decryptedFile := filepath.Join(tempDir, "decrypted_base92_legacy.txt")
+		err := os.WriteFile(inFile, plaintext, 0644)
+		if err != nil {
+			t.Fatalf("Failed to write input file: %v", err)
+		}
+
+		for _, tc := range []struct {
+			flag string
+			enc  *base92.Encoding
+		}{{"-9", base92.StdEncoding}, {"--base92-legacy", base92.DefaultEncoding}} {
+			outFile := filepath.Join(tempDir, "encrypted"+tc.flag+".txt")
+			err = run(context.Background(), []string{"encrypt", tc.flag, "-i", inFile, "-o", outFile, "-p", password})
+			if err != nil {
+				t.Fatalf("Failed to run encryption with %s: %v", tc.flag, err)
+			}
+			encoded, err := os.ReadFile(outFile)
+			if err != nil {
+				t.Fatalf("Failed to read encrypted file: %v", err)
+			}
+			decoded, err := tc.enc.DecodeString(string(encoded))
+			if err != nil {
+				t.Fatalf("Output of %s is not in the expected encoding: %v", tc.flag, err)
+			}
+			if _, err := argon2aes.Decrypt(decoded, []byte(password)); err != nil {
+				t.Errorf("Failed to decrypt output of %s: %v", tc.flag, err)
+			}
+
+			err = run(context.Background(), []string{"decrypt", tc.flag, "-i", outFile, "-o", decryptedFile, "-p", password})
+			if err != nil {
+				t.Fatalf("Failed to run decryption with %s: %v", tc.flag, err)
+			}
+		}
+
+		err = run(context.Background(), []string{"encrypt", "-9", "--base92-legacy", "-i", inFile, "-p", password})
+		if err == nil {
+			t.Error("Expected an error for two encodings, but got none")
+		}
+	})
+	// Test base92 with other alphabets
+	t.Run("Base92Alphabet", func(t *testing.T) {
+		inFile := filepath.Join(tempDir, "input_base92_alphabet.txt")
+		decryptedFile := filepath.Join(tempDir, "decrypted_base92_alphabet.txt")
+		err := os.WriteFile(inFile, plaintext, 0644)
+		if err != nil {
+			t.Fatalf("Failed to write input file: %v", err)
+		}
+
+		custom := "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789!#$%&()*+,-./:;<=>?@[]^_{|}~\"\\"
+		for _, tc := range []struct {
+			alphabet string
+			enc      *base92.Encoding
+		}{{"shell", base92.ShellEncoding}, {"json", base92.JSONEncoding}, {custom, nil}} {
+			if tc.enc == nil {
+				tc.enc, err = base92.NewEncoding(tc.alphabet)
+				if err != nil {
+					t.Fatalf("Invalid test alphabet: %v", err)
+				}
+			}
+			outFile := filepath.Join(tempDir, "encrypted_base92_alphabet.txt")
+			err = run(context.Background(), []string{"encrypt", "-9", "--alphabet", tc.alphabet, "-i", inFile, "-o", outFile, "-p", password})
+			if err != nil {
+				t.Fatalf("Failed to run encryption with alphabet %s: %v", tc.alphabet, err)
+			}
+			encoded, err := os.ReadFile(outFile)
+			if err != nil {
+				t.Fatalf("Failed to read encrypted file: %v", err)
+			}
+			if _, err := tc.enc.DecodeString(string(encoded)); err != nil {
+				t.Errorf("Output with alphabet %s is not in that alphabet: %v", tc.alphabet, err)
+			}
+			err = run(context.Background(), []string{"decrypt", "-9", "--alphabet", tc.alphabet, "-i", outFile, "-o", decryptedFile, "-p", password})
+			if err != nil {
+				t.Fatalf("Failed to run decryption with alphabet %s: %v", tc.alphabet, err)
+			}
+			os.Remove(outFile)
+		}
+	})
+	// Test every encoding by name
+	t.Run("Encoding", func(t *testing.T) {
+		inFile := filepath.Join(tempDir, "input_encoding.txt")
+		outFile := filepath.Join(tempDir, "encrypted_encoding.txt")
+		decryptedFile := filepath.Join(tempDir, "decrypted_encoding.txt")
+		err := os.WriteFile(inFile, plaintext, 0644)
+		if err != nil {
+			t.Fatalf("Failed to write input file: %v", err)
+		}
+
+		for _, name := range append(codec.Names(), "none") {
+			err = run(context.Background(), []string{"encrypt", "--encoding", name, "-i", inFile, "-o", outFile, "-p", password})
+			if err != nil {
+				t.Fatalf("Failed to run encryption with %s: %v", name, err)
+			}
+			encoded, err := os.ReadFile(outFile)
+			if err != nil {
+				t.Fatalf("Failed to read encrypted file: %v", err)
+			}
+			ciphertext := encoded
+			if c := codec.Lookup(name); c != nil {
+				if ciphertext, err = codec.DecodeString(c, string(encoded)); err != nil {
+					t.Fatalf("Output of --encoding %s is not in that encoding: %v", name, err)
+				}
+			}
+			if _, err := argon2aes.Decrypt(ciphertext, []byte(password)); err != nil {
+				t.Errorf("Failed to decrypt output of --encoding %s: %v", name, err)
+			}
+
+			err = run(context.Background(), []string{"decrypt", "--encoding", name, "-i", outFile, "-o", decryptedFile, "-p", password})
+			if err != nil {
+				t.Fatalf("Failed to run decryption with %s: %v", name, err)
+			}
+			decrypted, err := os.ReadFile(decryptedFile)
+			if err != nil {
+				t.Fatalf("Failed to read decrypted file: %v", err)
+			}
+			if !bytes.Equal(decrypted, plaintext) {
+				t.Errorf("Decrypted content with %s does not match original", name)
+			}
+		}
+	})
+	// Decrypt recognizes the encoding without a flag
+	t.Run("DetectEncoding", func(t *testing.T) {
+		inFile := filepath.Join(tempDir, "input_detect.txt")
+		outFile := filepath.Join(tempDir, "encrypted_detect.txt")
+		rekeyedFile := filepath.Join(tempDir, "rekeyed_detect.txt")
+		decryptedFile := filepath.Join(tempDir, "decrypted_detect.txt")
+		err := os.WriteFile(inFile, plaintext, 0644)
+		if err != nil {
+			t.Fatalf("Failed to write input file: %v", err)
+		}
+
+		for _, args := range [][]string{{}, {"-6"}, {"-u"}, {"-9"}, {"--encoding", "hex"}, {"--encoding", "base32"}, {"--encoding", "z85"}, {"--encoding", "base58"}, {"--base92-legacy"}} {
+			err = run(context.Background(), append([]string{"encrypt", "-i", inFile, "-o", outFile, "-p", password}, args...))
+			if err != nil {
+				t.Fatalf("Failed to run encryption with %v: %v", args, err)
+			}
+			err = run(context.Background(), []string{"decrypt", "-i", outFile, "-o", decryptedFile, "-p", password})
+			if err != nil {
+				t.Fatalf("Failed to decrypt output of %v without a flag: %v", args, err)
+			}
+			decrypted, err := os.ReadFile(decryptedFile)
+			if err != nil {
+				t.Fatalf("Failed to read decrypted file: %v", err)
+			}
+			if !bytes.Equal(decrypted, plaintext) {
+				t.Errorf("Decrypted content of %v does not match original", args)
+			}
+
+			// Rekeying keeps the encoding
+			err = run(context.Background(), []string{"rekey", "-i", outFile, "-o", rekeyedFile, "-p", password, "--new-passphrase", "new" + password})
+			if err != nil {
+				t.Fatalf("Failed to rekey output of %v: %v", args, err)
+			}
+			err = run(context.Background(), append([]string{"decrypt", "-i", rekeyedFile, "-o", decryptedFile, "-p", "new" + password}, args...))
+			if err != nil {
+				t.Errorf("Rekeyed output of %v is not in the same encoding: %v", args, err)
+			}
+		}
+
+		// Padded base64, as written by other tools
+		err = run(context.Background(), []string{"encrypt", "-i", inFile, "-o", outFile, "-p", password})
+		if err != nil {
+			t.Fatalf("Failed to run encryption: %v", err)
+		}
+		ciphertext, err := os.ReadFile(outFile)
+		if err != nil {
+			t.Fatalf("Failed to read encrypted file: %v", err)
+		}
+		for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding} {
+			encoded := enc.EncodeToString(ciphertext) + "\n"
+			if err := os.WriteFile(outFile, []byte(encoded), 0644); err != nil {
+				t.Fatalf("Failed to write encoded file: %v", err)
+			}
+			err = run(context.Background(), []string{"decrypt", "-i", outFile, "-o", decryptedFile, "-p", password})
+			if err != nil {
+				t.Errorf("Failed to decrypt padded base64 without a flag: %v", err)
+			}
+		}
+
+		// A damaged signature is reported as corrupt input, not as a
+		// wrong password
+		for _, tc := range []struct {
+			args   []string
+			damage byte
+		}{
+			{[]string{"-6"}, '!'},
+			{[]string{"--encoding", "hex"}, 'x'},
+			{[]string{"--encoding", "hex"}, 'f'},
+		} {
+			err = run(context.Background(), append([]string{"encrypt", "-i", inFile, "-o", outFile, "-p", password}, tc.args...))
+			if err != nil {
+				t.Fatalf("Failed to run encryption with %v: %v", tc.args, err)
+			}
+			encoded, err := os.ReadFile(outFile)
+			if err != nil {
+				t.Fatalf("Failed to read encrypted file: %v", err)
+			}
+			if encoded[2] == tc.damage {
+				encoded[2] = '0'
+			} else {
+				encoded[2] = tc.damage
+			}
+			if err := os.WriteFile(outFile, encoded, 0644); err != nil {
+				t.Fatalf("Failed to write encrypted file: %v", err)
+			}
+			err = run(context.Background(), []string{"decrypt", "-i", outFile, "-o", decryptedFile, "-p", password})
+			if exitCode(err) != exitCorrupt {
+				t.Errorf("Expected corrupt input for %v damaged with %q, got %v", tc.args, tc.damage, err)
+			}
+		}
+	})
+	// Files written by versions before the stream format, which have no
+	// header, in each encoding those versions wrote
+	t.Run("BaselineFormat", func(t *testing.T) {
+		const (
+			std64 = "yvwSASuNaGtwRKo7BaGhTzEECosgkiz9bF+mpf3iG0FUHPMwEbarS0ybwlmAfzs+ScbAqbmdOmjv9oiIOBxYPXcezh+x9rq9JA"
+			url64 = "yvwSASuNaGtwRKo7BaGhTzEECosgkiz9bF-mpf3iG0FUHPMwEbarS0ybwlmAfzs-ScbAqbmdOmjv9oiIOBxYPXcezh-x9rq9JA"
+			b92   = "8zWCnDBVQH-(A2P8(58v8oI7ad}B$r*>(pO0r-i?4zG9ovHzI8:DWaHhiv5nX$BaF?*71k^8y.,hnWg%qNagL(Mm7:"
+		)
+		raw, err := base64.RawStdEncoding.DecodeString(std64)
+		if err != nil {
+			t.Fatalf("Failed to decode fixed ciphertext: %v", err)
+		}
+		inFile := filepath.Join(tempDir, "baseline.txt")
+		decryptedFile := filepath.Join(tempDir, "decrypted_baseline.txt")
+
+		testCases := []struct {
+			name  string
+			input string
+			flag  string
+		}{
+			{"Binary", string(raw), ""},
+			{"Base64", std64, "-6"},
+			{"URL64", url64, "-u"},
+			{"Base92", b92, "-9"},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				if err := os.WriteFile(inFile, []byte(tc.input), 0644); err != nil {
+					t.Fatalf("Failed to write input file: %v", err)
+				}
+				argSets := [][]string{{"-d"}, {"decrypt"}}
+				if tc.flag != "" {
+					argSets = append(argSets, []string{"-d", tc.flag})
+				}
+				for _, args := range argSets {
+					err := run(context.Background(), append(args, "-i", inFile, "-o", decryptedFile, "-p", password))
+					if err != nil {
+						t.Fatalf("Failed to decrypt with %v: %v", args, err)
+					}
+					decrypted, err := os.ReadFile(decryptedFile)
+					if err != nil {
+						t.Fatalf("Failed to read decrypted file: %v", err)
+					}
+					if string(decrypted) != "Hello, World!" {
+						t.Errorf("Decrypted content with %v is %q", args, decrypted)
+					}
+				}
+			})
+		}
+
+		if err := os.WriteFile(inFile, []byte(b92), 0644); err != nil {
+			t.Fatalf("Failed to write input file: %v", err)
+		}
+		err = run(context.Background(), []string{"-d", "-i", inFile, "-o", decryptedFile, "-p", "wrong" + password})
+		if !errors.Is(err, argon2aes.ErrWrongPassword) {
+			t.Errorf("Expected a wrong password error, got %v", err)
+		}
+	})
+	// Test ASCII armor
+	t.Run("Armor", func(t *testing.T) {
+		inFile := filepath.Join(tempDir, "input_armor.txt")
+		outFile := filepath.Join(tempDir, "encrypted_armor.asc")
+		decryptedFile := filepath.Join(tempDir, "decrypted_armor.txt")
+		err := os.WriteFile(inFile, plaintext, 0644)
+		if err != nil {
+			t.Fatalf("Failed to write input file: %v", err)
+		}
+		err = run(context.Background(), []string{"encrypt", "--armor", "-i", inFile, "-o", outFile, "-p", password})
+		if err != nil {
+			t.Fatalf("Failed to run encryption with --armor: %v", err)
+		}
+		armored, err := os.ReadFile(outFile)
+		if err != nil {
+			t.Fatalf("Failed to read encrypted file: %v", err)
+		}
+		if !strings.HasPrefix(string(armored), "-----BEGIN A2A ENCRYPTED MESSAGE-----\n") {
+			t.Fatalf("Output of --armor is not armored:\n%s", armored)
+		}
+
+		// Pasted into an email, and decrypted with and without --armor
+		email := "Subject: backup\n\nHere it is:\n\n" + string(armored) + "\n-- \nSent from my phone\n"
+		if err := os.WriteFile(outFile, []byte(email), 0644); err != nil {
+			t.Fatalf("Failed to write email: %v", err)
+		}
+		for _, args := range [][]string{{"--armor"}, {}} {
+			err = run(context.Background(), append([]string{"decrypt", "-i", outFile, "-o", decryptedFile, "-p", password}, args...))
+			if err != nil {
+				t.Fatalf("Failed to decrypt armor with %v: %v", args, err)
+			}
+			decrypted, err := os.ReadFile(decryptedFile)
+			if err != nil {
+				t.Fatalf("Failed to read decrypted file: %v", err)
+			}
+			if !bytes.Equal(decrypted, plaintext) {
+				t.Errorf("Decrypted content with %v does not match original", args)
+			}
+		}
+	})
+	// Base92 copied from a terminal or email may be wrapped
+	t.Run("Base92Wrapped", func(t *testing.T) {
+		inFile := filepath.Join(tempDir, "input_base92_wrapped.txt")
+		outFile := filepath.Join(tempDir, "encrypted_base92_wrapped.txt")
+		decryptedFile := filepath.Join(tempDir, "decrypted_base92_wrapped.txt")
+		err := os.WriteFile(inFile, plaintext, 0644)
+		if err != nil {
+			t.Fatalf("Failed to write input file: %v", err)
+		}
+		err = run(context.Background(), []string{"encrypt", "-9", "-i", inFile, "-o", outFile, "-p", password})
+		if err != nil {
+			t.Fatalf("Failed to run encryption: %v", err)
+		}
+		encoded, err := os.ReadFile(outFile)
+		if err != nil {
+			t.Fatalf("Failed to read encrypted file: %v", err)
+		}
+		var wrapped []byte
+		for len(encoded) > 64 {
+			wrapped = append(append(wrapped, encoded[:64]...), '\n')
+			encoded = encoded[64:]
+		}
+		wrapped = append(append(wrapped, encoded...), "\r\n"...)
+		if err := os.WriteFile(outFile, wrapped, 0644); err != nil {
+			t.Fatalf("Failed to write wrapped file: %v", err)
+		}
+
+		err = run(context.Background(), []string{"decrypt", "-9", "-i", outFile, "-o", decryptedFile, "-p", password})
+		if err != nil {
+			t.Fatalf("Failed to decrypt wrapped base92: %v", err)
+		}
+		decrypted, err := os.ReadFile(decryptedFile)
+		if err != nil {
+			t.Fatalf("Failed to read decrypted file: %v", err)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Errorf("Decrypted content does not match original. Got %s, want %s", decrypted, plaintext)
+		}
+	})
+	// Test parallel chunk processing
+	t.Run("Jobs", func(t *testing.T) {
+		inFile := filepath.Join(tempDir, "input_jobs.txt")
+		outFile := filepath.Join(tempDir, "encrypted_jobs.bin")
+		decryptedFile := filepath.Join(tempDir, "decrypted_jobs.txt")
+
+		largePlaintext := bytes.Repeat(plaintext, 20000)
+		err := os.WriteFile(inFile, largePlaintext, 0644)
+		if err != nil {
+			t.Fatalf("Failed to write input file: %v", err)
+		}
+
+		// Encrypt with several jobs
+		err = run(context.Background(), []string{"encrypt", "-i", inFile, "-o", outFile, "-p", password, "-j", "4"})
+		if err != nil {
+			t.Fatalf("Failed to run encryption with jobs: %v", err)
+		}
+
+		// Decrypt with a single job
+		err = run(context.Background(), []string{"decrypt", "-i", outFile, "-o", decryptedFile, "-p", password, "-j", "1"})
+		if err != nil {
+			t.Fatalf("Failed to run decryption with jobs: %v", err)
+		}
+
+		// Read the decrypted file
+		decrypted, err := os.ReadFile(decryptedFile)
+		if err != nil {
+			t.Fatalf("Failed to read decrypted file: %v", err)
+		}
+
+		// Compare the decrypted content with the original plaintext
+		if !bytes.Equal(decrypted, largePlaintext) {
+			t.Errorf("Decrypted content does not match original")
+		}
+	})
+	// Test cancellation removes partial output
+	t.Run("Canceled", func(t *testing.T) {
+		inFile := filepath.Join(tempDir, "input_canceled.txt")
+		outFile := filepath.Join(tempDir, "encrypted_canceled.bin")
+
+		err := os.WriteFile(inFile, plaintext, 0644)
+		if err != nil {
+			t.Fatalf("Failed to write input file: %v", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err = run(ctx, []string{"encrypt", "-i", inFile, "-o", outFile, "-p", password})
+		if err != context.Canceled {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+
+		// Check that the output file was removed
+		if _, err := os.Stat(outFile); !os.IsNotExist(err) {
+			t.Errorf("Partial output file was not removed")
+		}
+
+		// Also while waiting for more input on stdin
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatalf("Failed to create pipe: %v", err)
+		}
+		defer w.Close()
+		oldStdin := os.Stdin
+		os.Stdin = r
+		defer func() { os.Stdin = oldStdin }()
+		if _, err := w.Write(plaintext); err != nil {
+			t.Fatalf("Failed to write to pipe: %v", err)
+		}
+
+		ctx, cancel = context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() {
+			done <- run(ctx, []string{"encrypt", "-o", outFile, "-p", password, "--time", "1", "--memory", "64"})
+		}()
+		time.Sleep(200 * time.Millisecond)
+		cancel()
+		select {
+		case err = <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Encryption did not stop while waiting for input")
+		}
+		if err != context.Canceled {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+		if _, err := os.Stat(outFile); !os.IsNotExist(err) {
+			t.Errorf("Partial output file was not removed")
+		}
+	})
+	// Test padding
+	t.Run("Padding", func(t *testing.T) {
+		shortFile := filepath.Join(tempDir, "input_short.txt")
+		longFile := filepath.Join(tempDir, "input_long.txt")
+		decryptedFile := filepath.Join(tempDir, "decrypted_padded.txt")
+
+		err := os.WriteFile(shortFile, plaintext, 0644)
+		if err != nil {
+			t.Fatalf("Failed to write input file: %v", err)
+		}
+		longPlaintext := bytes.Repeat(plaintext, 50)
+		err = os.WriteFile(longFile, longPlaintext, 0644)
+		if err != nil {
+			t.Fatalf("Failed to write input file: %v", err)
+		}
+
+		// Encrypt both files with the same block padding
+		var sizes []int64
+		for _, inFile := range []string{shortFile, longFile} {
+			err = run(context.Background(), []string{"encrypt", "-i", inFile, "-o", inFile + ".a2a", "-p", password, "--pad", "1024"})
+			if err != nil {
+				t.Fatalf("Failed to run encryption with padding: %v", err)
+			}
+
+			info, err := os.Stat(inFile + ".a2a")
+			if err != nil {
+				t.Fatalf("Failed to stat encrypted file: %v", err)
+			}
+			sizes = append(sizes, info.Size())
+		}
+		if sizes[0] != sizes[1] {
+			t.Errorf("Padded outputs differ in size: %v", sizes)
+		}
+
+		// Decrypt the long file
+		err = run(context.Background(), []string{"decrypt", "-i", longFile + ".a2a", "-o", decryptedFile, "-p", password})
+		if err != nil {
+			t.Fatalf("Failed to run decryption with padding: %v", err)
+		}
+
+		decrypted, err := os.ReadFile(decryptedFile)
+		if err != nil {
+			t.Fatalf("Failed to read decrypted file: %v", err)
+		}
+		if !bytes.Equal(decrypted, longPlaintext) {
+			t.Errorf("Decrypted content does not match original")
+		}
+	})
+	// Test compression
+	t.Run("Compression", func(t *testing.T) {
+		inFile := filepath.Join(tempDir, "input_compress.txt")
+		outFile := filepath.Join(tempDir, "encrypted_compress.bin")
+		decryptedFile := filepath.Join(tempDir, "decrypted_compress.txt")
+
+		largePlaintext := bytes.Repeat(plaintext, 1000)
+		err := os.WriteFile(inFile, largePlaintext, 0644)
+		if err != nil {
+			t.Fatalf("Failed to write input file: %v", err)
+		}
+
+		// Encrypt with gzip compression
+		err = run(context.Background(), []string{"encrypt", "-i", inFile, "-o", outFile, "-p", password, "--compress", "gzip"})
+		if err != nil {
+			t.Fatalf("Failed to run encryption with compression: %v", err)
+		}
+
+		info, err := os.Stat(outFile)
+		if err != nil {
+			t.Fatalf("Failed to stat encrypted file: %v", err)
+		}
+		if info.Size() >= int64(len(largePlaintext)) {
+			t.Errorf("Encrypted file was not compressed: %d bytes", info.Size())
+		}
+
+		// Decrypting with a too small maximum size fails
+		args := []string{"decrypt", "-i", outFile, "-o", decryptedFile, "-p", password}
+		err = run(context.Background(), append(args, "--max-size", "100"))
+		if err == nil {
+			t.Errorf("Expected an error when exceeding the maximum size, but got none")
+		}
+
+		// A negative limit is a usage error, and the largest one works
+		err = run(context.Background(), append(args, "--max-size", "-1"))
+		if exitCode(err) != exitUsage {
+			t.Errorf("Expected a usage error for a negative --max-size, got %v", err)
+		}
+		err = run(context.Background(), append(args, "--max-size", "9223372036854775807"))
+		if err != nil {
+			t.Errorf("Failed to run decryption with the largest --max-size: %v", err)
+		}
+
+		// Compressed files have a default limit, which --max-size raises
+		oldMax := defaultMaxCompressed
+		defaultMaxCompressed = 100
+		err = run(context.Background(), args)
+		if err == nil {
+			t.Errorf("Expected an error when exceeding the default maximum size, but got none")
+		}
+		err = run(context.Background(), append(args, "--max-size", strconv.Itoa(len(largePlaintext))))
+		defaultMaxCompressed = oldMax
+		if err != nil {
+			t.Errorf("Failed to run decryption with a larger --max-size: %v", err)
+		}
+
+		// Decrypt within the default limit
+		err = run(context.Background(), args)
+		if err != nil {
+			t.Fatalf("Failed to run decryption with compression: %v", err)
+		}
+
+		decrypted, err := os.ReadFile(decryptedFile)
+		if err != nil {
+			t.Fatalf("Failed to read decrypted file: %v", err)
+		}
+		if !bytes.Equal(decrypted, largePlaintext) {
+			t.Errorf("Decrypted content does not match original")
+		}
+	})
+	// Test info
+	t.Run("Info", func(t *testing.T) {
+		inFile := filepath.Join(tempDir, "input_info.txt")
+		outFile := filepath.Join(tempDir, "encrypted_info.bin")
+
+		err := os.WriteFile(inFile, plaintext, 0644)
+		if err != nil {
+			t.Fatalf("Failed to write input file: %v", err)
+		}
+
+		// Encrypt with compression
+		err = run(context.Background(), []string{"encrypt", "-i", inFile, "-o", outFile, "-p", password, "--compress", "flate"})
+		if err != nil {
+			t.Fatalf("Failed to run encryption: %v", err)
+		}
+
+		// Run info with JSON output
+		out, err := capture("info", "--json", outFile)
+		if err != nil {
+			t.Fatalf("Failed to run info: %v", err)
+		}
+
+		var result struct {
+			File        string `json:"file"`
+			Legacy      bool   `json:"legacy"`
+			Compression string `json:"compression"`
+		}
+		if err := json.Unmarshal([]byte(out), &result); err != nil {
+			t.Fatalf("Failed to parse info output %q: %v", out, err)
+		}
+		if result.File != outFile || result.Legacy || result.Compression != "flate" {
+			t.Errorf("Unexpected info output: %s", out)
+		}
+	})
+	// Test verify
+	t.Run("Verify", func(t *testing.T) {
+		inFile := filepath.Join(tempDir, "input_verify.txt")
+		goodFile := filepath.Join(tempDir, "encrypted_good.bin")
+		badFile := filepath.Join(tempDir, "encrypted_bad.bin")
+
+		err := os.WriteFile(inFile, plaintext, 0644)
+		if err != nil {
+			t.Fatalf("Failed to write input file: %v", err)
+		}
+
+		err = run(context.Background(), []string{"encrypt", "-i", inFile, "-o", goodFile, "-p", password})
+		if err != nil {
+			t.Fatalf("Failed to run encryption: %v", err)
+		}
+
+		// Corrupt a copy of the encrypted file
+		encrypted, err := os.ReadFile(goodFile)
+		if err != nil {
+			t.Fatalf("Failed to read encrypted file: %v", err)
+		}
+		encrypted[len(encrypted)-1]++
+		err = os.WriteFile(badFile, encrypted, 0644)
+		if err != nil {
+			t.Fatalf("Failed to write corrupted file: %v", err)
+		}
+
+		out, err := capture("verify", "-p", password, goodFile)
+		if err != nil {
+			t.Errorf("Verification of a good file failed: %v", err)
+		}
+		if out != goodFile+": OK\n" {
+			t.Errorf("Unexpected verify output: %q", out)
+		}
+
+		out, err = capture("verify", "-p", password, goodFile, badFile)
+		if err == nil {
+			t.Errorf("Expected an error when verifying a corrupt file, but got none")
+		}
+		if !strings.Contains(out, badFile+": FAILED") {
+			t.Errorf("Corrupt file was not reported: %q", out)
+		}
+	})
+	// Test rekey
+	t.Run("Rekey", func(t *testing.T) {
+		inFile := filepath.Join(tempDir, "input_rekey.txt")
+		oldFile := filepath.Join(tempDir, "encrypted_old.bin")
+		newFile := filepath.Join(tempDir, "encrypted_new.bin")
+		decryptedFile := filepath.Join(tempDir, "decrypted_rekey.txt")
+		newPassword := "zq7Rw!vB3m@Lp"
+
+		err := os.WriteFile(inFile, plaintext, 0644)
+		if err != nil {
+			t.Fatalf("Failed to write input file: %v", err)
+		}
+
+		err = run(context.Background(), []string{"encrypt", "-i", inFile, "-o", oldFile, "-p", password, "-6"})
+		if err != nil {
+			t.Fatalf("Failed to run encryption: %v", err)
+		}
+
+		// Rekeying onto the input file is refused
+		err = run(context.Background(), []string{"rekey", "-i", oldFile, "-o", oldFile, "-p", password, "--new-passphrase", newPassword})
+		if err == nil {
+			t.Errorf("Expected an error when rekeying in place, but got none")
+		}
+
+		// Rekeying with the wrong passphrase fails without leaving output
+		err = run(context.Background(), []string{"rekey", "-i", oldFile, "-o", newFile, "-p", "wrong", "--new-passphrase", newPassword, "-6"})
+		if err == nil {
+			t.Errorf("Expected an error when rekeying with the wrong passphrase, but got none")
+		}
+		if _, err := os.Stat(newFile); !os.IsNotExist(err) {
+			t.Errorf("Output file was created despite the wrong passphrase")
+		}
+
+		err = run(context.Background(), []string{"rekey", "-i", oldFile, "-o", newFile, "-p", password, "--new-passphrase", newPassword, "-6"})
+		if err != nil {
+			t.Fatalf("Failed to run rekey: %v", err)
+		}
+
+		// The old passphrase no longer works
+		err = run(context.Background(), []string{"decrypt", "-i", newFile, "-o", decryptedFile, "-p", password, "-6"})
+		if err == nil {
+			t.Errorf("Expected an error when decrypting with the old passphrase, but got none")
+		}
+
+		err = run(context.Background(), []string{"decrypt", "-i", newFile, "-o", decryptedFile, "-p", newPassword, "-6"})
+		if err != nil {
+			t.Fatalf("Failed to run decryption with the new passphrase: %v", err)
+		}
+		decrypted, err := os.ReadFile(decryptedFile)
+		if err != nil {
+			t.Fatalf("Failed to read decrypted file: %v", err)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Errorf("Decrypted content does not match original. Got %s, want %s", decrypted, plaintext)
+		}
+	})
+	// Test keygen
+	t.Run("Keygen", func(t *testing.T) {
+		out, err := capture("keygen")
+		if err != nil {
+			t.Fatalf("Failed to run keygen: %v", err)
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSuffix(out, "\n"))
+		if err != nil || len(key) != 32 {
+			t.Errorf("Unexpected keygen output %q: %v", out, err)
+		}
+
+		keyFile := filepath.Join(tempDir, "key.txt")
+		if err := run(context.Background(), []string{"keygen", "-u", "-o", keyFile}); err != nil {
+			t.Fatalf("Failed to run keygen: %v", err)
+		}
+		info, err := os.Stat(keyFile)
+		if err != nil {
+			t.Fatalf("Failed to stat key file: %v", err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("Expected key file mode 0600, got %v", info.Mode().Perm())
+		}
+
+		// An existing key file is never overwritten
+		if err := run(context.Background(), []string{"keygen", "-o", keyFile}); err == nil {
+			t.Errorf("Expected an error when overwriting a key file, but got none")
+		}
+	})
+	// Test gen
+	t.Run("Gen", func(t *testing.T) {
+		inFile := filepath.Join(tempDir, "input_gen.txt")
+		outFile := filepath.Join(tempDir, "encrypted_gen.bin")
+		decryptedFile := filepath.Join(tempDir, "decrypted_gen.txt")
+
+		err := os.WriteFile(inFile, plaintext, 0644)
+		if err != nil {
+			t.Fatalf("Failed to write input file: %v", err)
+		}
+
+		out, err := capture("gen", "passphrase", "--words", "4")
+		if err != nil {
+			t.Fatalf("Failed to run gen passphrase: %v", err)
+		}
+		if n := len(strings.Fields(out)); n != 4 {
+			t.Errorf("Expected 4 words, got %q", out)
+		}
+
+		// A base92 key works with --key
+		out, err = capture("gen", "key", "-9")
+		if err != nil {
+			t.Fatalf("Failed to run gen key: %v", err)
+		}
+		key := strings.TrimSuffix(out, "\n")
+		if decoded, err := base92.DefaultEncoding.DecodeString(key); err != nil || len(decoded) != 32 {
+			t.Errorf("Unexpected gen key output %q: %v", out, err)
+		}
+		err = run(context.Background(), []string{"encrypt", "-i", inFile, "-o", outFile, "-k", key})
+		if err != nil {
+			t.Fatalf("Failed to run encryption with a base92 key: %v", err)
+		}
+		err = run(context.Background(), []string{"decrypt", "-i", outFile, "-o", decryptedFile, "-k", key})
+		if err != nil {
+			t.Fatalf("Failed to run decryption with a base92 key: %v", err)
+		}
+
+		// So does a key in words, typed back in capitals on one line
+		out, err = capture("gen", "key", "--encoding", "words")
+		if err != nil {
+			t.Fatalf("Failed to run gen key --encoding words: %v", err)
+		}
+		if n := len(strings.Fields(out)); n != 32 {
+			t.Errorf("Expected 32 words, got %q", out)
+		}
+		key = strings.ToUpper(strings.Join(strings.Fields(out), " "))
+		err = run(context.Background(), []string{"encrypt", "-i", inFile, "-o", outFile, "-k", key})
+		if err != nil {
+			t.Fatalf("Failed to run encryption with a key in words: %v", err)
+		}
+		err = run(context.Background(), []string{"decrypt", "-i", outFile, "-o", decryptedFile, "-k", key})
+		if err != nil {
+			t.Fatalf("Failed to run decryption with a key in words: %v", err)
+		}
+		// A fixed key, since a random one passes the check of the first
+		// group with its words swapped once in 64 times
+		fixed := make([]byte, 32)
+		for i := range fixed {
+			fixed[i] = byte(i)
+		}
+		misheard := strings.Fields(words.Encode(fixed))
+		misheard[0], misheard[2] = misheard[2], misheard[0]
+		err = run(context.Background(), []string{"decrypt", "-i", outFile, "-o", decryptedFile, "-k", strings.Join(misheard, " ")})
+		if err == nil || !strings.Contains(err.Error(), "at word 1") {
+			t.Errorf("Expected an error at word 1 for a misheard key, got %v", err)
+		}
+
+		for _, args := range [][]string{{"gen"}, {"gen", "password"}, {"gen", "passphrase", "--words", "0"}, {"gen", "key", "--encoding", "hex"}, {"keygen", "-9", "--encoding", "words"}} {
+			if _, err := capture(args...); err == nil {
+				t.Errorf("Expected an error for %q, but got none", args)
+			}
+		}
+
+		// Encrypt with a generated passphrase
+		out, err = capture("encrypt", "-i", inFile, "-o", outFile, "--generate")
+		if err != nil {
+			t.Fatalf("Failed to run encryption with --generate: %v", err)
+		}
+		generated, ok := strings.CutPrefix(strings.TrimSuffix(out, "\n"), "Generated passphrase: ")
+		if !ok || len(strings.Fields(generated)) != 6 {
+			t.Fatalf("Unexpected output for --generate: %q", out)
+		}
+		err = run(context.Background(), []string{"decrypt", "-i", outFile, "-o", decryptedFile, "-p", generated})
+		if err != nil {
+			t.Fatalf("Failed to run decryption with the generated passphrase: %v", err)
+		}
+		decrypted, err := os.ReadFile(decryptedFile)
+		if err != nil {
+			t.Fatalf("Failed to read decrypted file: %v", err)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Errorf("Decrypted content does not match original. Got %s, want %s", decrypted, plaintext)
+		}
+
+		if _, err := capture("encrypt", "-i", inFile, "-o", outFile, "--generate", "-p", password); err == nil {
+			t.Errorf("Expected an error for --generate with --passphrase, but got none")
+		}
+		if _, err := capture("-d", "-i", outFile, "-o", decryptedFile, "--generate"); err == nil {
+			t.Errorf("Expected an error for --generate when decrypting, but got none")
+		}
+	})
+	// The output must not overwrite the input it streams from
+	t.Run("InPlace", func(t *testing.T) {
+		inFile := filepath.Join(tempDir, "input_inplace.txt")
+		link := filepath.Join(tempDir, "link_inplace.txt")
+		if err := os.WriteFile(inFile, plaintext, 0644); err != nil {
+			t.Fatalf("Failed to write input file: %v", err)
+		}
+		if err := os.Symlink(inFile, link); err != nil {
+			t.Fatalf("Failed to create symlink: %v", err)
+		}
+		for _, args := range [][]string{
+			{"encrypt", "-i", inFile, "-o", inFile, "-p", password},
+			{"-e", "-i", inFile, "-o", link, "-p", password},
+			{"decrypt", "-i", inFile, "-o", inFile, "-p", password},
+		} {
+			if err := run(context.Background(), args); exitCode(err) != exitUsage {
+				t.Errorf("Expected a usage error for %q, got %v", args, err)
+			}
+		}
+		content, err := os.ReadFile(inFile)
+		if err != nil {
+			t.Fatalf("Failed to read input file: %v", err)
+		}
+		if !bytes.Equal(content, plaintext) {
+			t.Errorf("Input file was modified: %q", content)
+		}
+	})
+	// Test paper
+	t.Run("Paper", func(t *testing.T) {
+		keyFile := filepath.Join(tempDir, "key_paper.bin")
+		paperFile := filepath.Join(tempDir, "key_paper.txt")
+		decodedFile := filepath.Join(tempDir, "key_paper_decoded.bin")
+		key, err := argon2aes.GenerateKey()
+		if err != nil {
+			t.Fatalf("Failed to generate key: %v", err)
+		}
+		if err := os.WriteFile(keyFile, key, 0600); err != nil {
+			t.Fatalf("Failed to write key file: %v", err)
+		}
+
+		if err := run(context.Background(), []string{"paper", "encode", "-i", keyFile, "-o", paperFile}); err != nil {
+			t.Fatalf("Failed to run paper encode: %v", err)
+		}
+		backup, err := os.ReadFile(paperFile)
+		if err != nil {
+			t.Fatalf("Failed to read paper backup: %v", err)
+		}
+
+		// Typed back in lowercase with a mistake in the first line
+		lines := strings.Split(strings.ToLower(string(backup)), "\n")
+		typo := []byte(lines[1])
+		if typo[3] == 'x' {
+			typo[3] = 'y'
+		} else {
+			typo[3] = 'x'
+		}
+		lines[1] = string(typo)
+		if err := os.WriteFile(paperFile, []byte(strings.Join(lines, "\n")), 0600); err != nil {
+			t.Fatalf("Failed to write paper backup: %v", err)
+		}
+		out, err := capture("paper", "decode", "-i", paperFile, "-o", decodedFile)
+		if err != nil {
+			t.Fatalf("Failed to run paper decode: %v", err)
+		}
+		if out != "Corrected line 2, character 4\n" {
+			t.Errorf("Unexpected paper decode output %q", out)
+		}
+		decoded, err := os.ReadFile(decodedFile)
+		if err != nil {
+			t.Fatalf("Failed to read decoded file: %v", err)
+		}
+		if !bytes.Equal(decoded, key) {
+			t.Errorf("Decoded key does not match original")
+		}
+		info, err := os.Stat(decodedFile)
+		if err != nil {
+			t.Fatalf("Failed to stat decoded file: %v", err)
+		}
+		if perm := info.Mode().Perm(); perm != 0600 {
+			t.Errorf("Expected decoded file mode 0600, got %o", perm)
+		}
+
+		// Input too large for a backup is refused rather than cut short
+		largeFile := filepath.Join(tempDir, "large_paper.bin")
+		if err := os.WriteFile(largeFile, make([]byte, paper.MaxSize+1), 0600); err != nil {
+			t.Fatalf("Failed to write large file: %v", err)
+		}
+		_, err = capture("paper", "encode", "-i", largeFile)
+		if err == nil || !strings.Contains(err.Error(), "input too large") {
+			t.Errorf("Expected an input too large error, got %v", err)
+		}
+
+		// Ciphertext on paper is detected when decrypting
+		inFile := filepath.Join(tempDir, "input_paper.txt")
+		outFile := filepath.Join(tempDir, "encrypted_paper.txt")
+		decryptedFile := filepath.Join(tempDir, "decrypted_paper.txt")
+		if err := os.WriteFile(inFile, plaintext, 0644); err != nil {
+			t.Fatalf("Failed to write input file: %v", err)
+		}
+		err = run(context.Background(), []string{"encrypt", "--encoding", "paper", "-i", inFile, "-o", outFile, "-p", password})
+		if err != nil {
+			t.Fatalf("Failed to run encryption with --encoding paper: %v", err)
+		}
+		err = run(context.Background(), []string{"decrypt", "-i", outFile, "-o", decryptedFile, "-p", password})
+		if err != nil {
+			t.Fatalf("Failed to run decryption of paper: %v", err)
+		}
+		decrypted, err := os.ReadFile(decryptedFile)
+		if err != nil {
+			t.Fatalf("Failed to read decrypted file: %v", err)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Errorf("Decrypted content does not match original. Got %s, want %s", decrypted, plaintext)
+		}
+
+		for _, args := range [][]string{{"paper"}, {"paper", "print"}} {
+			if _, err := capture(args...); err == nil {
+				t.Errorf("Expected an error for %q, but got none", args)
+			}
+		}
+	})
+	// Test bench
+	t.Run("Bench", func(t *testing.T) {
+		out, err := capture("bench", "--size", "1", "-j", "2")
+		if err != nil {
+			t.Fatalf("Failed to run bench: %v", err)
+		}
+		for _, want := range []string{"Key derivation:", "Encrypt:", "Decrypt:"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("Bench output is missing %q: %q", want, out)
+			}
+		}
+	})
 }
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/presbrey/argon2aes/pkg/codec"
+	"github.com/presbrey/argon2aes/pkg/paper"
+	"github.com/spf13/pflag"
+)
+
+func paperFlags(fs *pflag.FlagSet, f *flags) {
+	fs.StringVarP(&f.inputFile, "in", "i", "-", "Input file (default: stdin)")
+	fs.StringVarP(&f.outputFile, "out", "o", "-", "Output file (default: stdout)")
+}
+
+// maxPaperText is the most text paper decode reads, more than the largest
+// backup takes with room for comments and spacing.
+const maxPaperText = paper.MaxSize * 4
+
+// runPaper converts a file, such as a key, to a layout for printing and
+// typing back in, or back again.
+func runPaper(ctx context.Context, f *flags, args []string) error {
+	if len(args) != 1 || (args[0] != "encode" && args[0] != "decode") {
+		return usageErrorf("paper requires one argument: encode or decode")
+	}
+	input, err := openInput(ctx, f.inputFile)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+	limit := paper.MaxSize
+	if args[0] == "decode" {
+		limit = maxPaperText
+	}
+	data, err := io.ReadAll(io.LimitReader(input, int64(limit)+1))
+	if err != nil {
+		return err
+	}
+	if len(data) > limit {
+		return fmt.Errorf("input too large: a paper backup holds at most %d bytes", paper.MaxSize)
+	}
+
+	if args[0] == "encode" {
+		text, err := paper.Encode(data)
+		if err != nil {
+			return err
+		}
+		// The backup is as secret as the data, so it gets the same
+		// treatment as a generated key.
+		return writeSecret(f.outputFile, strings.TrimSuffix(text, "\n"))
+	}
+
+	decoded, corrections, err := paper.Decode(string(data))
+	for _, c := range corrections {
+		fmt.Fprintf(os.Stderr, "Corrected line %d, character %d\n", c.Line, c.Column)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", codec.ErrCorrupt, err)
+	}
+	return writePrivate(f.outputFile, decoded)
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/presbrey/argon2aes/pkg/base92"
+	"github.com/presbrey/argon2aes/pkg/strength"
+	"github.com/presbrey/argon2aes/pkg/words"
+	"github.com/spf13/pflag"
+	"golang.org/x/term"
+)
+
+func passphraseFlags(fs *pflag.FlagSet, f *flags) {
+	fs.StringVarP(&f.key, "key", "k", "", "Encryption key (base64, base92 or words)")
+	fs.StringVarP(&f.passphrase, "passphrase", "p", "", "Encryption passphrase")
+	fs.StringVar(&f.passphraseEnv, "passphrase-env", "", "Read the passphrase from the named environment variable, used exactly as set")
+	fs.StringVar(&f.passphraseFile, "passphrase-file", "", "Read the passphrase from a file; one trailing newline (\\n or \\r\\n) is removed")
+	fs.IntVar(&f.passphraseFD, "passphrase-fd", -1, "Read the passphrase from an open file descriptor until EOF; one trailing newline (\\n or \\r\\n) is removed")
+}
+
+func strengthFlags(fs *pflag.FlagSet, f *flags) {
+	fs.Float64Var(&f.minEntropy, "min-entropy", 50, "Minimum estimated entropy of a new passphrase, in bits")
+	fs.StringVar(&f.weakPassphrase, "weak-passphrase", "warn", "What to do with a passphrase below --min-entropy: warn or refuse")
+}
+
+// checkStrength applies the --min-entropy policy to a new passphrase. Keys
+// given with --key are random and not checked.
+func (f *flags) checkStrength(passphrase []byte) error {
+	if f.weakPassphrase != "warn" && f.weakPassphrase != "refuse" {
+		return usageErrorf("invalid --weak-passphrase %q: must be warn or refuse", f.weakPassphrase)
+	}
+	result := strength.Estimate(string(passphrase))
+	if result.Entropy >= f.minEntropy {
+		return nil
+	}
+
+	msg := fmt.Sprintf("passphrase is weak: estimated %.0f bits of entropy, below the minimum of %.0f", result.Entropy, f.minEntropy)
+	if len(result.Warnings) > 0 {
+		msg += " (" + strings.Join(result.Warnings, ", ") + ")"
+	}
+	if f.weakPassphrase == "refuse" {
+		return fmt.Errorf("%s", msg)
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %s\n", msg)
+	return nil
+}
+
+// passphraseGiven reports whether any passphrase or key option was set.
+func (f *flags) passphraseGiven() bool {
+	return f.key != "" || f.passphrase != "" || f.passphraseEnv != "" || f.passphraseFile != "" || f.passphraseFD >= 0
+}
+
+// readPassphrase returns the secret given by exactly one of the passphrase
+// flags, prompting for a passphrase if none is set. With confirm, a
+// prompted passphrase must be entered twice.
+func (f *flags) readPassphrase(confirm bool) ([]byte, error) {
+	sources := 0
+	for _, set := range []bool{
+		f.key != "",
+		f.passphrase != "",
+		f.passphraseEnv != "",
+		f.passphraseFile != "",
+		f.passphraseFD >= 0,
+	} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return nil, usageErrorf("can only use one of --key, --passphrase, --passphrase-env, --passphrase-file or --passphrase-fd")
+	}
+
+	var secret []byte
+	switch {
+	case f.passphraseEnv != "":
+		value, ok := os.LookupEnv(f.passphraseEnv)
+		if !ok {
+			return nil, fmt.Errorf("environment variable %s is not set", f.passphraseEnv)
+		}
+		secret = []byte(value)
+	case f.passphraseFile != "":
+		data, err := os.ReadFile(f.passphraseFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading passphrase: %w", err)
+		}
+		secret = trimNewline(data)
+	case f.passphraseFD >= 0:
+		if f.passphraseFD == 0 && f.inputFile == "-" {
+			return nil, usageErrorf("cannot read both the passphrase and the input from stdin")
+		}
+		file := os.NewFile(uintptr(f.passphraseFD), fmt.Sprintf("fd %d", f.passphraseFD))
+		if file == nil {
+			return nil, fmt.Errorf("invalid file descriptor %d", f.passphraseFD)
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error reading passphrase: %w", err)
+		}
+		secret = trimNewline(data)
+	default:
+		confirmPrompt := ""
+		if confirm {
+			confirmPrompt = "Confirm passphrase: "
+		}
+		return readSecret(f.key, f.passphrase, "Enter passphrase: ", confirmPrompt)
+	}
+
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("passphrase cannot be empty")
+	}
+	return secret, nil
+}
+
+// trimNewline removes a single trailing "\n" or "\r\n", as left by echo or
+// a text editor. Any other whitespace is part of the passphrase.
+func trimNewline(b []byte) []byte {
+	if !bytes.HasSuffix(b, []byte("\n")) {
+		return b
+	}
+	return bytes.TrimSuffix(b[:len(b)-1], []byte("\r"))
+}
+
+// readSecret decodes key if set, as base64, base92 or words, otherwise
+// returns passphrase or prompts for one. A non-empty confirmPrompt asks for
+// the passphrase a second time.
+func readSecret(key, passphrase, prompt, confirmPrompt string) ([]byte, error) {
+	var secret []byte
+	var err error
+
+	if strings.ContainsAny(strings.TrimSpace(key), " \t\n") {
+		// Keys printed by gen key --encoding words.
+		secret, err = words.Decode(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key: %v", err)
+		}
+	} else if key != "" && onlyOf(key, base64Chars+url64Chars) {
+		var encoding *base64.Encoding
+		if strings.ContainsAny(key, "-_") {
+			encoding = base64.URLEncoding
+		} else {
+			encoding = base64.StdEncoding
+		}
+		// Base92 accepts any string of its alphabet, so a key that could be
+		// base64 is never read as base92: a mistyped base64 key fails
+		// rather than standing for another key.
+		if secret, err = encoding.DecodeString(key); err != nil {
+			return nil, fmt.Errorf("invalid key: not valid base64, and base92 keys use characters that base64 does not")
+		}
+	} else if key != "" {
+		// Keys printed by gen key --base92, which always use a character
+		// that base64 does not.
+		if secret, err = base92.DefaultEncoding.DecodeString(key); err != nil {
+			return nil, fmt.Errorf("invalid key. Must be base64 or base92 encoded")
+		}
+	} else if passphrase != "" {
+		secret = []byte(passphrase)
+	} else {
+		secret, err = promptPassphrase(prompt, confirmPrompt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("passphrase cannot be empty")
+	}
+	return secret, nil
+}
+
+// promptPassphrase reads a passphrase from the controlling terminal with
+// the prompt on stderr, leaving stdin and stdout free for data.
+func promptPassphrase(prompt, confirmPrompt string) ([]byte, error) {
+	tty, err := os.OpenFile(ttyPath, os.O_RDWR, 0)
+	if err != nil {
+		return nil, errNoTerminal
+	}
+	defer tty.Close()
+	if !term.IsTerminal(int(tty.Fd())) {
+		return nil, errNoTerminal
+	}
+
+	secret, err := readPassword(tty, prompt)
+	if err != nil || confirmPrompt == "" || len(secret) == 0 {
+		return secret, err
+	}
+	again, err := readPassword(tty, confirmPrompt)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(secret, again) {
+		return nil, fmt.Errorf("passphrases do not match")
+	}
+	return secret, nil
+}
+
+var errNoTerminal = fmt.Errorf("no terminal to prompt for a passphrase; use --passphrase-file, --passphrase-fd or --passphrase-env")
+
+func readPassword(tty *os.File, prompt string) ([]byte, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	secret, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(os.Stderr) // Print a newline after the password input
+	if err != nil {
+		return nil, fmt.Errorf("error reading passphrase: %w", err)
+	}
+	return secret, nil
+}
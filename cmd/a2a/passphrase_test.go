@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTrimNewline(t *testing.T) {
+	testCases := []struct {
+		input, want string
+	}{
+		{"secret", "secret"},
+		{"secret\n", "secret"},
+		{"secret\r\n", "secret"},
+		{"secret\n\n", "secret\n"},
+		{"secret\r", "secret\r"},
+		{" secret \n", " secret "},
+		{"\n", ""},
+	}
+
+	for _, tc := range testCases {
+		if got := string(trimNewline([]byte(tc.input))); got != tc.want {
+			t.Errorf("trimNewline(%q) = %q, want %q", tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestPromptWithoutTerminal(t *testing.T) {
+	// A regular file stands in for a missing controlling terminal.
+	oldTTYPath := ttyPath
+	ttyPath = filepath.Join(t.TempDir(), "tty")
+	defer func() { ttyPath = oldTTYPath }()
+
+	if _, err := readSecret("", "", "Enter passphrase: ", ""); err != errNoTerminal {
+		t.Errorf("Expected %v, got %v", errNoTerminal, err)
+	}
+	if err := os.WriteFile(ttyPath, []byte("secret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, err := readSecret("", "", "Enter passphrase: ", ""); err != errNoTerminal {
+		t.Errorf("Expected %v, got %v", errNoTerminal, err)
+	}
+}
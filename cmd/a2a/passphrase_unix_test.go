@@ -0,0 +1,60 @@
+//go:build unix
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+func TestPassphraseFD(t *testing.T) {
+	tempDir := t.TempDir()
+	inFile := filepath.Join(tempDir, "input.txt")
+	outFile := filepath.Join(tempDir, "encrypted.bin")
+	decryptedFile := filepath.Join(tempDir, "decrypted.txt")
+	plaintext := []byte("Hello, World!")
+
+	if err := os.WriteFile(inFile, plaintext, 0644); err != nil {
+		t.Fatalf("Failed to write input file: %v", err)
+	}
+
+	// passphraseFD writes the passphrase to a pipe and returns a duplicate
+	// of its read end, which a2a closes after reading it.
+	passphraseFD := func(passphrase string) string {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatalf("Failed to create pipe: %v", err)
+		}
+		defer r.Close()
+		w.WriteString(passphrase)
+		w.Close()
+
+		fd, err := syscall.Dup(int(r.Fd()))
+		if err != nil {
+			t.Fatalf("Failed to duplicate file descriptor: %v", err)
+		}
+		return strconv.Itoa(fd)
+	}
+
+	err := run(context.Background(), []string{"encrypt", "-i", inFile, "-o", outFile, "--passphrase-fd", passphraseFD("Xk9#mP2q-fd\n")})
+	if err != nil {
+		t.Fatalf("Failed to run encryption with --passphrase-fd: %v", err)
+	}
+	err = run(context.Background(), []string{"decrypt", "-i", outFile, "-o", decryptedFile, "-p", "Xk9#mP2q-fd"})
+	if err != nil {
+		t.Fatalf("Failed to run decryption: %v", err)
+	}
+
+	decrypted, err := os.ReadFile(decryptedFile)
+	if err != nil {
+		t.Fatalf("Failed to read decrypted file: %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("Decrypted content does not match original. Got %s, want %s", decrypted, plaintext)
+	}
+}
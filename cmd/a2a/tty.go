@@ -0,0 +1,6 @@
+//go:build !windows
+
+package main
+
+// ttyPath is the controlling terminal, from which passphrases are read.
+var ttyPath = "/dev/tty"
@@ -0,0 +1,4 @@
+package main
+
+// ttyPath is the console input buffer, from which passphrases are read.
+var ttyPath = "CONIN$"
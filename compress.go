@@ -0,0 +1,140 @@
+package argon2aes
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// Compression selects how plaintext is compressed before it is encrypted.
+// The choice is recorded in the stream header, so readers decompress
+// automatically.
+type Compression uint8
+
+const (
+	CompressNone Compression = iota
+	CompressFlate
+	CompressGzip
+)
+
+// ParseCompression parses the names returned by Compression.String.
+func ParseCompression(s string) (Compression, error) {
+	switch s {
+	case "", "none":
+		return CompressNone, nil
+	case "flate":
+		return CompressFlate, nil
+	case "gzip":
+		return CompressGzip, nil
+	}
+	return CompressNone, fmt.Errorf("invalid compression %q", s)
+}
+
+func (c Compression) String() string {
+	switch c {
+	case CompressNone:
+		return "none"
+	case CompressFlate:
+		return "flate"
+	case CompressGzip:
+		return "gzip"
+	}
+	return fmt.Sprintf("Compression(%d)", uint8(c))
+}
+
+type compressWriter struct {
+	zw io.WriteCloser
+	w  io.WriteCloser
+}
+
+func newCompressWriter(w io.WriteCloser, c Compression) (io.WriteCloser, error) {
+	var zw io.WriteCloser
+	var err error
+	switch c {
+	case CompressFlate:
+		zw, err = flate.NewWriter(w, flate.DefaultCompression)
+	case CompressGzip:
+		zw = gzip.NewWriter(w)
+	default:
+		err = fmt.Errorf("unsupported compression %d", c)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &compressWriter{zw: zw, w: w}, nil
+}
+
+func (c *compressWriter) Write(p []byte) (int, error) {
+	return c.zw.Write(p)
+}
+
+func (c *compressWriter) Close() error {
+	if err := c.zw.Close(); err != nil {
+		return err
+	}
+	return c.w.Close()
+}
+
+// decompressReader decompresses a decrypted stream. Once the compressed
+// data ends it reads the stream to the end, so that the final chunk is
+// always authenticated and trailing data is rejected.
+type decompressReader struct {
+	src io.Reader
+	zr  io.Reader
+}
+
+func newDecompressReader(r io.Reader, c Compression) (io.Reader, error) {
+	// Both decompressors read an io.ByteReader without buffering ahead, so
+	// whatever follows the compressed data is left in src.
+	src := bufio.NewReader(r)
+
+	var zr io.Reader
+	var err error
+	switch c {
+	case CompressFlate:
+		zr = flate.NewReader(src)
+	case CompressGzip:
+		zr, err = gzip.NewReader(src)
+	default:
+		err = fmt.Errorf("unsupported compression %d", c)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &decompressReader{src: src, zr: zr}, nil
+}
+
+func (d *decompressReader) Read(p []byte) (int, error) {
+	n, err := d.zr.Read(p)
+	if err == io.EOF {
+		var b [1]byte
+		if m, err := io.ReadFull(d.src, b[:]); m > 0 {
+			return n, corruptf("trailing data after compressed payload")
+		} else if err != io.EOF {
+			return n, err
+		}
+	}
+	return n, err
+}
+
+// limitReader fails once more than n bytes have been read, rather than
+// silently truncating like io.LimitReader.
+type limitReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	// One byte more than the limit is read to detect exceeding it.
+	if l.n < int64(len(p))-1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) > l.n {
+		return int(l.n), fmt.Errorf("plaintext exceeds maximum size")
+	}
+	l.n -= int64(n)
+	return n, err
+}
@@ -0,0 +1,139 @@
+package argon2aes
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/rand"
+	"math"
+	"testing"
+)
+
+func TestCompressionRoundTrip(t *testing.T) {
+	password := []byte("password")
+	data := bytes.Repeat([]byte(`{"level":"info","msg":"request served"}`+"\n"), 1000)
+
+	for _, c := range []Compression{CompressFlate, CompressGzip} {
+		t.Run(c.String(), func(t *testing.T) {
+			encrypted, err := Encrypt(data, password, WithCompression(c), WithChunkSize(256), WithJobs(4))
+			if err != nil {
+				t.Fatalf("Encrypt failed: %v", err)
+			}
+			if len(encrypted) > len(data)/10 {
+				t.Errorf("Expected compressed output, got %d bytes for %d bytes of input", len(encrypted), len(data))
+			}
+
+			decrypted, err := Decrypt(encrypted, password, WithJobs(4))
+			if err != nil {
+				t.Fatalf("Decrypt failed: %v", err)
+			}
+			if !bytes.Equal(data, decrypted) {
+				t.Error("Decrypted data doesn't match original")
+			}
+		})
+	}
+}
+
+func TestCompressionWithPadding(t *testing.T) {
+	password := []byte("password")
+	data := bytes.Repeat([]byte("compressible "), 100)
+
+	encrypted, err := Encrypt(data, password, WithCompression(CompressGzip), WithPadding(PadPowerOfTwo), WithChunkSize(16))
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	decrypted, err := Decrypt(encrypted, password)
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if !bytes.Equal(data, decrypted) {
+		t.Error("Decrypted data doesn't match original")
+	}
+}
+
+func TestMaxSize(t *testing.T) {
+	password := []byte("password")
+	data := make([]byte, 100000)
+
+	encrypted, err := Encrypt(data, password, WithCompression(CompressFlate))
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+
+	if _, err := Decrypt(encrypted, password, WithMaxSize(int64(len(data)-1))); err == nil {
+		t.Error("Expected an error when exceeding the maximum size, but got none")
+	}
+	if _, err := Decrypt(encrypted, password, WithMaxSize(int64(len(data)))); err != nil {
+		t.Errorf("Decrypt failed at exactly the maximum size: %v", err)
+	}
+
+	legacy, err := Encrypt(data[:100], password)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if _, err := Decrypt(legacy, password, WithMaxSize(99)); err == nil {
+		t.Error("Expected an error when exceeding the maximum size, but got none")
+	}
+	if _, err := Decrypt(encrypted, password, WithMaxSize(math.MaxInt64)); err != nil {
+		t.Errorf("Decrypt failed with the largest maximum size: %v", err)
+	}
+	if _, err := Decrypt(encrypted, password, WithMaxSize(-1)); err == nil {
+		t.Error("Expected an error for a negative maximum size, but got none")
+	}
+}
+
+func TestCompressionTrailingData(t *testing.T) {
+	password := []byte("password")
+	h := &header{
+		version:   streamVersion,
+		cipher:    cipherAESGCM,
+		kdf:       kdfArgon2id,
+		time:      time,
+		memory:    memory,
+		threads:   threads,
+		chunkSize: DefaultChunkSize,
+		compress:  uint8(CompressFlate),
+	}
+	rand.Read(h.salt[:])
+	keys, err := deriveStreamKeys(context.Background(), h, password, newOptions(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	b := h.marshal()
+	buf.Write(append(b, keys.headerMAC(b)...))
+	sw := newStreamWriter(context.Background(), &buf, keys, newOptions(nil))
+	zw, _ := flate.NewWriter(sw, flate.DefaultCompression)
+	zw.Write([]byte("compressed"))
+	zw.Close()
+	sw.Write([]byte("trailing"))
+	sw.Close()
+
+	if _, err := Decrypt(buf.Bytes(), password); err == nil {
+		t.Error("Expected an error for trailing data after the compressed payload, but got none")
+	}
+}
+
+func TestCompressionReaderAt(t *testing.T) {
+	password := []byte("password")
+	encrypted, err := Encrypt([]byte("data"), password, WithCompression(CompressGzip))
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if _, _, err := OpenReaderAt(bytes.NewReader(encrypted), int64(len(encrypted)), password); err == nil {
+		t.Error("Expected an error for random access to a compressed stream, but got none")
+	}
+}
+
+func TestParseCompression(t *testing.T) {
+	for _, c := range []Compression{CompressNone, CompressFlate, CompressGzip} {
+		parsed, err := ParseCompression(c.String())
+		if err != nil || parsed != c {
+			t.Errorf("ParseCompression(%q) = %v, %v", c.String(), parsed, err)
+		}
+	}
+	if _, err := ParseCompression("zstd"); err == nil {
+		t.Error("ParseCompression(\"zstd\") should return an error")
+	}
+}
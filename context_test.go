@@ -0,0 +1,68 @@
+package argon2aes
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestContextCanceledBeforeStart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	password := []byte("password")
+
+	if _, err := EncryptContext(ctx, []byte("data"), password); !errors.Is(err, context.Canceled) {
+		t.Errorf("EncryptContext: expected context.Canceled, got %v", err)
+	}
+	if _, err := DecryptContext(ctx, make([]byte, 100), password); !errors.Is(err, context.Canceled) {
+		t.Errorf("DecryptContext: expected context.Canceled, got %v", err)
+	}
+	if _, err := NewWriterContext(ctx, io.Discard, password); !errors.Is(err, context.Canceled) {
+		t.Errorf("NewWriterContext: expected context.Canceled, got %v", err)
+	}
+}
+
+func TestContextCanceledBetweenChunks(t *testing.T) {
+	password := []byte("password")
+	data := bytes.Repeat([]byte("H"), 1024)
+
+	t.Run("Writer", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		w, err := NewWriterContext(ctx, io.Discard, password, WithChunkSize(64))
+		if err != nil {
+			t.Fatalf("NewWriterContext failed: %v", err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		cancel()
+		if _, err := w.Write(data); !errors.Is(err, context.Canceled) {
+			t.Errorf("Write: expected context.Canceled, got %v", err)
+		}
+		if err := w.Close(); !errors.Is(err, context.Canceled) {
+			t.Errorf("Close: expected context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("Reader", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		encrypted := encryptStream(t, data, password, WithChunkSize(64))
+		r, err := NewReaderContext(ctx, bytes.NewReader(encrypted), password)
+		if err != nil {
+			t.Fatalf("NewReaderContext failed: %v", err)
+		}
+		if _, err := io.ReadFull(r, make([]byte, 100)); err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		cancel()
+		if _, err := io.ReadAll(r); !errors.Is(err, context.Canceled) {
+			t.Errorf("Read: expected context.Canceled, got %v", err)
+		}
+	})
+}
@@ -1,10 +1,13 @@
 package argon2aes
 
 import (
+	"bytes"
+	"context"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
 	"fmt"
+	"io"
 
 	"golang.org/x/crypto/argon2"
 )
@@ -19,11 +22,52 @@ const (
 
 // DeriveKey generates an Argon2 key from a password and salt
 func DeriveKey(password []byte, salt []byte) []byte {
+	return argon2IDKey(password, salt, time, memory, threads)
+}
+
+func argon2IDKey(password, salt []byte, time, memory uint32, threads uint8) []byte {
 	return argon2.IDKey(password, salt, time, memory, threads, keyLength)
 }
 
-// Encrypt encrypts plaintext using AES-GCM with an Argon2 key
-func Encrypt(plaintext []byte, password []byte) ([]byte, error) {
+// deriveKeyContext wraps DeriveKey, which cannot be interrupted, with
+// cancellation checks before and after it.
+func deriveKeyContext(ctx context.Context, password []byte, salt []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	key := DeriveKey(password, salt)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Encrypt encrypts plaintext using AES-GCM with an Argon2 key. Without
+// options the result is the compact format of salt, nonce and ciphertext,
+// which is exactly 60 bytes longer than plaintext. Passing any option, such
+// as WithPadding, produces the chunked stream format written by NewWriter.
+func Encrypt(plaintext []byte, password []byte, opts ...Option) ([]byte, error) {
+	return EncryptContext(context.Background(), plaintext, password, opts...)
+}
+
+// EncryptContext is like Encrypt but returns ctx.Err() if ctx is done
+// before or after key derivation.
+func EncryptContext(ctx context.Context, plaintext []byte, password []byte, opts ...Option) ([]byte, error) {
+	if len(opts) > 0 {
+		var buf bytes.Buffer
+		w, err := NewWriterContext(ctx, &buf, password, opts...)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(plaintext); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
 	if len(password) == 0 {
 		return nil, fmt.Errorf("password cannot be blank")
 	}
@@ -33,7 +77,10 @@ func Encrypt(plaintext []byte, password []byte) ([]byte, error) {
 		return nil, err
 	}
 
-	key := DeriveKey(password, salt)
+	key, err := deriveKeyContext(ctx, password, salt)
+	if err != nil {
+		return nil, err
+	}
 
 	block, err := aes.NewCipher(key)
 	if err != nil {
@@ -59,14 +106,32 @@ func Encrypt(plaintext []byte, password []byte) ([]byte, error) {
 	return encrypted, nil
 }
 
-// Decrypt decrypts ciphertext using AES-GCM with an Argon2 key
-func Decrypt(data []byte, password []byte) ([]byte, error) {
+// Decrypt decrypts ciphertext using AES-GCM with an Argon2 key. It accepts
+// both the output of Encrypt and streams written by NewWriter.
+func Decrypt(data []byte, password []byte, opts ...Option) ([]byte, error) {
+	return DecryptContext(context.Background(), data, password, opts...)
+}
+
+// DecryptContext is like Decrypt but returns ctx.Err() if ctx is done
+// before or after key derivation or between chunks of a stream.
+func DecryptContext(ctx context.Context, data []byte, password []byte, opts ...Option) ([]byte, error) {
+	r, err := NewReaderContext(ctx, bytes.NewReader(data), password, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func decryptLegacy(ctx context.Context, data []byte, password []byte, o *options) ([]byte, error) {
 	if len(data) < saltLength {
-		return nil, fmt.Errorf("ciphertext too short")
+		return nil, corruptf("ciphertext too short")
 	}
 	salt, data := data[:saltLength], data[saltLength:]
 
-	key := DeriveKey(password, salt)
+	key, err := o.masterKey(ctx, password, KeyParams{Salt: salt, Time: time, Memory: memory, Threads: threads})
+	if err != nil {
+		return nil, err
+	}
 
 	block, err := aes.NewCipher(key)
 	if err != nil {
@@ -80,13 +145,13 @@ func Decrypt(data []byte, password []byte) ([]byte, error) {
 
 	nonceSize := gcm.NonceSize()
 	if len(data) < nonceSize {
-		return nil, fmt.Errorf("ciphertext too short")
+		return nil, corruptf("ciphertext too short")
 	}
 
 	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
 	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w or corrupt ciphertext", ErrWrongPassword)
 	}
 
 	return plaintext, nil
@@ -2,6 +2,7 @@ package argon2aes
 
 import (
 	"bytes"
+	"errors"
 	"testing"
 )
 
@@ -50,6 +51,8 @@ func TestDecryptWithWrongPassword(t *testing.T) {
 	_, err = Decrypt(encrypted, wrongPassword)
 	if err == nil {
 		t.Error("Expected an error when decrypting with wrong password, but got none")
+	} else if !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("Expected ErrWrongPassword, got %v", err)
 	}
 }
 
@@ -90,6 +93,8 @@ func TestDecryptShortCiphertext(t *testing.T) {
 				t.Error("Expected an error when decrypting short ciphertext, but got none")
 			} else if err.Error() != tc.expectedErr {
 				t.Errorf("Expected error '%s', but got '%s'", tc.expectedErr, err.Error())
+			} else if !errors.Is(err, ErrCorrupt) {
+				t.Errorf("Expected ErrCorrupt, got %v", err)
 			}
 		})
 	}
@@ -0,0 +1,31 @@
+package argon2aes
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrWrongPassword matches errors from decryption when the password
+	// or key does not authenticate the input. For the output of Encrypt,
+	// a wrong password cannot be told apart from corrupt ciphertext.
+	ErrWrongPassword = errors.New("incorrect password")
+
+	// ErrCorrupt matches errors from decryption when the input is not a
+	// valid, complete ciphertext, such as a malformed header, truncation
+	// or a chunk that fails authentication under the right password.
+	ErrCorrupt = errors.New("corrupt input")
+)
+
+// corruptError keeps the message of its error while matching ErrCorrupt.
+type corruptError struct {
+	err error
+}
+
+func corruptf(format string, args ...any) error {
+	return &corruptError{fmt.Errorf(format, args...)}
+}
+
+func (e *corruptError) Error() string { return e.err.Error() }
+
+func (e *corruptError) Unwrap() []error { return []error{ErrCorrupt, e.err} }
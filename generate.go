@@ -0,0 +1,61 @@
+package argon2aes
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// DefaultPassphraseWords is the number of words that gives a generated
+// passphrase about 77 bits of entropy.
+const DefaultPassphraseWords = 6
+
+// effWordList is the EFF large wordlist for dice-generated passphrases
+// (https://www.eff.org/dice), licensed under CC BY 3.0 US. Each line holds
+// five dice rolls and a word.
+//
+//go:embed eff_large_wordlist.txt
+var effWordList string
+
+var passphraseWords = func() []string {
+	lines := strings.Split(strings.TrimSpace(effWordList), "\n")
+	words := make([]string, len(lines))
+	for i, line := range lines {
+		_, words[i], _ = strings.Cut(line, "\t")
+	}
+	return words
+}()
+
+// GeneratePassphrase returns the given number of words chosen uniformly at
+// random from the EFF large wordlist, separated by spaces. Each word adds
+// about 12.9 bits of entropy.
+func GeneratePassphrase(words int) (string, error) {
+	if words < 1 {
+		return "", fmt.Errorf("passphrase must have at least one word")
+	}
+
+	n := big.NewInt(int64(len(passphraseWords)))
+	chosen := make([]string, words)
+	for i := range chosen {
+		k, err := rand.Int(rand.Reader, n)
+		if err != nil {
+			return "", err
+		}
+		chosen[i] = passphraseWords[k.Int64()]
+	}
+	return strings.Join(chosen, " "), nil
+}
+
+// GenerateKey returns a random 32-byte key, the same size as a key derived
+// from a passphrase. It is meant to be stored as text, such as base64,
+// base92 or words (see package words), and passed to Encrypt and Decrypt as
+// the password.
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, keyLength)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
@@ -0,0 +1,63 @@
+package argon2aes
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPassphraseWords(t *testing.T) {
+	if len(passphraseWords) != 7776 {
+		t.Fatalf("Expected 7776 words, got %d", len(passphraseWords))
+	}
+	if passphraseWords[0] != "abacus" || passphraseWords[len(passphraseWords)-1] != "zoom" {
+		t.Errorf("Unexpected word list bounds: %q, %q", passphraseWords[0], passphraseWords[len(passphraseWords)-1])
+	}
+
+	seen := make(map[string]bool)
+	for _, word := range passphraseWords {
+		if word == "" || strings.ContainsAny(word, " \t\r") || seen[word] {
+			t.Fatalf("Invalid or duplicate word %q", word)
+		}
+		seen[word] = true
+	}
+}
+
+func TestGeneratePassphrase(t *testing.T) {
+	for _, words := range []int{1, DefaultPassphraseWords, 12} {
+		passphrase, err := GeneratePassphrase(words)
+		if err != nil {
+			t.Fatalf("GeneratePassphrase(%d) failed: %v", words, err)
+		}
+		if n := len(strings.Fields(passphrase)); n != words {
+			t.Errorf("GeneratePassphrase(%d) returned %d words: %q", words, n, passphrase)
+		}
+	}
+
+	a, _ := GeneratePassphrase(DefaultPassphraseWords)
+	b, _ := GeneratePassphrase(DefaultPassphraseWords)
+	if a == b {
+		t.Errorf("Two generated passphrases are equal: %q", a)
+	}
+
+	if _, err := GeneratePassphrase(0); err == nil {
+		t.Error("Expected an error for zero words, but got none")
+	}
+}
+
+func TestGenerateKey(t *testing.T) {
+	key1, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	key2, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	if len(key1) != keyLength {
+		t.Errorf("Expected key length %d, got %d", keyLength, len(key1))
+	}
+	if bytes.Equal(key1, key2) {
+		t.Error("Two generated keys are equal")
+	}
+}
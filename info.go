@@ -0,0 +1,117 @@
+package argon2aes
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// Info describes an encrypted file as far as it can be read without the
+// password.
+type Info struct {
+	// Legacy is set for the headerless output of Encrypt, whose parameters
+	// are implied rather than recorded.
+	Legacy  bool `json:"legacy"`
+	Version int  `json:"version"`
+
+	Cipher  string `json:"cipher"`
+	KDF     string `json:"kdf"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+	Salt    []byte `json:"salt"`
+
+	ChunkSize   int    `json:"chunk_size,omitempty"`
+	Padding     string `json:"padding"`
+	Compression string `json:"compression"`
+
+	// PayloadSize is the number of bytes following the header or, for
+	// legacy files, following the salt.
+	PayloadSize int64 `json:"payload_size"`
+}
+
+// Inspect reads the header of an encrypted file without decrypting it. It
+// consumes r to the end to determine the payload size. Input without a
+// stream header is reported as a legacy file.
+func Inspect(r io.Reader) (Info, error) {
+	br := bufio.NewReader(r)
+
+	b, err := br.Peek(headerLength)
+	if !bytes.HasPrefix(b, magic) {
+		if err != nil && err != io.EOF {
+			return Info{}, err
+		}
+		return inspectLegacy(br)
+	}
+	if err != nil {
+		if err == io.EOF {
+			return Info{}, corruptf("invalid stream header")
+		}
+		return Info{}, err
+	}
+
+	h, err := parseHeader(b)
+	if err != nil {
+		return Info{}, err
+	}
+	if _, err := br.Discard(headerLength); err != nil {
+		return Info{}, err
+	}
+	n, err := io.Copy(io.Discard, br)
+	if err != nil {
+		return Info{}, err
+	}
+
+	return Info{
+		Version:     int(h.version),
+		Cipher:      "AES-256-GCM",
+		KDF:         "argon2id",
+		Time:        h.time,
+		Memory:      h.memory,
+		Threads:     h.threads,
+		Salt:        h.salt[:],
+		ChunkSize:   int(h.chunkSize),
+		Padding:     paddingName(h.padding),
+		Compression: Compression(h.compress).String(),
+		PayloadSize: n,
+	}, nil
+}
+
+func inspectLegacy(r io.Reader) (Info, error) {
+	salt := make([]byte, saltLength)
+	if _, err := io.ReadFull(r, salt); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return Info{}, corruptf("ciphertext too short")
+		}
+		return Info{}, err
+	}
+	n, err := io.Copy(io.Discard, r)
+	if err != nil {
+		return Info{}, err
+	}
+	if n < 12+tagLength {
+		return Info{}, corruptf("ciphertext too short")
+	}
+
+	return Info{
+		Legacy:      true,
+		Cipher:      "AES-256-GCM",
+		KDF:         "argon2id",
+		Time:        time,
+		Memory:      memory,
+		Threads:     threads,
+		Salt:        salt,
+		Padding:     "none",
+		Compression: CompressNone.String(),
+		PayloadSize: n,
+	}, nil
+}
+
+// paddingName names a padding scheme recorded in a header. Block padding
+// is reported without its size, which the header does not record.
+func paddingName(scheme uint8) string {
+	if scheme == padBlock {
+		return "block"
+	}
+	return Padding{scheme: scheme}.String()
+}
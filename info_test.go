@@ -0,0 +1,82 @@
+package argon2aes
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	password := []byte("password")
+	data := bytes.Repeat([]byte("J"), 1000)
+
+	encrypted, err := Encrypt(data, password, WithChunkSize(256), WithPadding(PadBlock(512)), WithCompression(CompressGzip))
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+
+	info, err := Inspect(bytes.NewReader(encrypted))
+	if err != nil {
+		t.Fatalf("Inspect failed: %v", err)
+	}
+
+	want := Info{
+		Version:     streamVersion,
+		Cipher:      "AES-256-GCM",
+		KDF:         "argon2id",
+		Time:        time,
+		Memory:      memory,
+		Threads:     threads,
+		Salt:        encrypted[28:60],
+		ChunkSize:   256,
+		Padding:     "block",
+		Compression: "gzip",
+		PayloadSize: int64(len(encrypted) - headerLength),
+	}
+	if info.Legacy || info.Version != want.Version || info.Cipher != want.Cipher || info.KDF != want.KDF ||
+		info.Time != want.Time || info.Memory != want.Memory || info.Threads != want.Threads ||
+		!bytes.Equal(info.Salt, want.Salt) || info.ChunkSize != want.ChunkSize || info.Padding != want.Padding ||
+		info.Compression != want.Compression || info.PayloadSize != want.PayloadSize {
+		t.Errorf("Inspect = %+v, want %+v", info, want)
+	}
+}
+
+func TestInspectLegacy(t *testing.T) {
+	encrypted, err := Encrypt([]byte("Hello, World!"), []byte("password"))
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+
+	info, err := Inspect(bytes.NewReader(encrypted))
+	if err != nil {
+		t.Fatalf("Inspect failed: %v", err)
+	}
+	if !info.Legacy || info.Version != 0 {
+		t.Errorf("Expected a legacy file, got %+v", info)
+	}
+	if !bytes.Equal(info.Salt, encrypted[:saltLength]) {
+		t.Errorf("Expected salt %x, got %x", encrypted[:saltLength], info.Salt)
+	}
+	if info.PayloadSize != int64(len(encrypted)-saltLength) {
+		t.Errorf("Expected payload size %d, got %d", len(encrypted)-saltLength, info.PayloadSize)
+	}
+}
+
+func TestInspectErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		data []byte
+	}{
+		{"Empty", []byte{}},
+		{"ShortLegacy", make([]byte, 50)},
+		{"ShortHeader", magic},
+		{"BadVersion", append(append(bytes.Clone(magic), 2), make([]byte, headerLength)...)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Inspect(bytes.NewReader(tc.data)); err == nil {
+				t.Error("Expected an error, but got none")
+			}
+		})
+	}
+}
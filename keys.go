@@ -0,0 +1,106 @@
+package argon2aes
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+)
+
+// The Argon2id cost used unless WithKeyParams selects another.
+const (
+	DefaultTime    = time
+	DefaultMemory  = memory // in KiB
+	DefaultThreads = threads
+)
+
+// KeyParams are the inputs to Argon2id besides the password: the salt and
+// cost parameters recorded in a stream header.
+type KeyParams struct {
+	Salt    []byte
+	Time    uint32
+	Memory  uint32 // in KiB
+	Threads uint8
+}
+
+// NewKeyParams returns the default cost parameters with a fresh random
+// salt.
+func NewKeyParams() (KeyParams, error) {
+	p := KeyParams{Salt: make([]byte, saltLength), Time: time, Memory: memory, Threads: threads}
+	if _, err := rand.Read(p.Salt); err != nil {
+		return KeyParams{}, err
+	}
+	return p, nil
+}
+
+func (p KeyParams) validate() error {
+	if len(p.Salt) != saltLength {
+		return fmt.Errorf("salt must be %d bytes", saltLength)
+	}
+	if p.Time == 0 || p.Time > maxTime || p.Threads == 0 ||
+		p.Memory < 8*uint32(p.Threads) || p.Memory > maxMemory {
+		return fmt.Errorf("invalid key derivation parameters")
+	}
+	return nil
+}
+
+// DeriveMasterKey runs Argon2id on password with p. Every key of a stream
+// is derived from the result together with the stream's random seed, so a
+// master key can be kept for a session to open streams sharing p without
+// repeating the slow derivation. Like DeriveKey it cannot be interrupted;
+// ctx is checked before and after it.
+func DeriveMasterKey(ctx context.Context, password []byte, p KeyParams) ([]byte, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	key := argon2IDKey(password, p.Salt, p.Time, p.Memory, p.Threads)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// A KeyFunc returns the master key for p, as DeriveMasterKey would for the
+// right password, for example from a cache of unlocked keys.
+type KeyFunc func(ctx context.Context, p KeyParams) ([]byte, error)
+
+// WithKeyFunc makes NewWriter and NewReader obtain the master key from fn
+// instead of deriving it from the password, which may then be nil. Decrypt
+// uses it for the output of Encrypt as well, whose key is the master key
+// for its salt and the default cost.
+func WithKeyFunc(fn KeyFunc) Option {
+	return func(o *options) {
+		o.keyFunc = fn
+	}
+}
+
+// WithKeyParams makes NewWriter use the salt and cost in p. A nil salt is
+// replaced by a fresh random one, so p can select just the cost. Streams
+// written with the same salt share a master key, but each still has its
+// own payload key because of its random seed.
+func WithKeyParams(p KeyParams) Option {
+	return func(o *options) {
+		o.keyParams = &p
+	}
+}
+
+// masterKey returns the master key for p from the KeyFunc if one is set,
+// and otherwise derives it from password.
+func (o *options) masterKey(ctx context.Context, password []byte, p KeyParams) ([]byte, error) {
+	if o.keyFunc == nil {
+		return DeriveMasterKey(ctx, password, p)
+	}
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	key, err := o.keyFunc(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != keyLength {
+		return nil, fmt.Errorf("master key must be %d bytes", keyLength)
+	}
+	return key, nil
+}
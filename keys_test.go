@@ -0,0 +1,131 @@
+package argon2aes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestKeyFunc(t *testing.T) {
+	password := []byte("password")
+	data := []byte("Hello, World!")
+
+	params, err := NewKeyParams()
+	if err != nil {
+		t.Fatalf("NewKeyParams failed: %v", err)
+	}
+	master, err := DeriveMasterKey(context.Background(), password, params)
+	if err != nil {
+		t.Fatalf("DeriveMasterKey failed: %v", err)
+	}
+
+	calls := 0
+	keyFunc := WithKeyFunc(func(ctx context.Context, p KeyParams) ([]byte, error) {
+		calls++
+		if !bytes.Equal(p.Salt, params.Salt) {
+			return nil, fmt.Errorf("unknown salt")
+		}
+		return master, nil
+	})
+
+	// Streams written with the same parameters share the master key but
+	// not their ciphertext.
+	a, err := Encrypt(data, nil, keyFunc, WithKeyParams(params))
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	b, err := Encrypt(data, nil, keyFunc, WithKeyParams(params))
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if bytes.Equal(a[headerMACOffset:], b[headerMACOffset:]) {
+		t.Error("Streams with the same key parameters have the same ciphertext")
+	}
+
+	for _, encrypted := range [][]byte{a, b} {
+		// Both the password and the cached key open the stream.
+		decrypted, err := Decrypt(encrypted, password)
+		if err != nil {
+			t.Fatalf("Decrypt with password failed: %v", err)
+		}
+		if !bytes.Equal(decrypted, data) {
+			t.Error("Decrypted data doesn't match original")
+		}
+		decrypted, err = Decrypt(encrypted, nil, keyFunc)
+		if err != nil {
+			t.Fatalf("Decrypt with key func failed: %v", err)
+		}
+		if !bytes.Equal(decrypted, data) {
+			t.Error("Decrypted data doesn't match original")
+		}
+	}
+	if calls != 4 {
+		t.Errorf("Expected 4 calls to the key func, got %d", calls)
+	}
+
+	// A stream with another salt is rejected by the key func.
+	other, err := Encrypt(data, password, WithChunkSize(16))
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if _, err := Decrypt(other, nil, keyFunc); err == nil {
+		t.Error("Expected an error for an unknown salt, but got none")
+	}
+}
+
+func TestKeyFuncLegacy(t *testing.T) {
+	password := []byte("password")
+	encrypted, err := Encrypt([]byte("legacy"), password)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+
+	decrypted, err := Decrypt(encrypted, nil, WithKeyFunc(func(ctx context.Context, p KeyParams) ([]byte, error) {
+		return DeriveMasterKey(ctx, password, p)
+	}))
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if string(decrypted) != "legacy" {
+		t.Errorf("Expected %q, got %q", "legacy", decrypted)
+	}
+}
+
+func TestKeyParams(t *testing.T) {
+	password := []byte("password")
+
+	// A nil salt selects only the cost.
+	encrypted, err := Encrypt([]byte("data"), password, WithKeyParams(KeyParams{Time: 1, Memory: 1024, Threads: 1}))
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	info, err := Inspect(bytes.NewReader(encrypted))
+	if err != nil {
+		t.Fatalf("Inspect failed: %v", err)
+	}
+	if info.Time != 1 || info.Memory != 1024 || info.Threads != 1 || bytes.Equal(info.Salt, make([]byte, saltLength)) {
+		t.Errorf("Unexpected parameters: %+v", info)
+	}
+	if _, err := Decrypt(encrypted, password); err != nil {
+		t.Errorf("Decrypt failed: %v", err)
+	}
+
+	for _, p := range []KeyParams{
+		{Time: 0, Memory: 1024, Threads: 1},
+		{Time: 1, Memory: 1, Threads: 1},
+		{Time: 1, Memory: 1024, Threads: 0},
+		{Salt: []byte("short"), Time: 1, Memory: 1024, Threads: 1},
+	} {
+		if _, err := Encrypt([]byte("data"), password, WithKeyParams(p)); err == nil {
+			t.Errorf("Expected an error for %+v, but got none", p)
+		}
+	}
+
+	keyFunc := WithKeyFunc(func(ctx context.Context, p KeyParams) ([]byte, error) {
+		return []byte("short"), nil
+	})
+	if _, err := Encrypt([]byte("data"), nil, keyFunc); err == nil {
+		t.Error("Expected an error for a short master key, but got none")
+	}
+}
@@ -0,0 +1,137 @@
+package argon2aes
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+)
+
+const (
+	padNone = iota
+	padPADME
+	padPowerOfTwo
+	padBlock
+)
+
+// A Padding scheme rounds the plaintext length up before encryption so that
+// the ciphertext does not reveal the exact size of its contents. Padding is
+// added inside the encrypted payload as a 0x80 byte followed by zeros, which
+// lets readers strip it without knowing the scheme.
+type Padding struct {
+	scheme uint8
+	block  int64
+}
+
+var (
+	// PadNone disables padding.
+	PadNone = Padding{}
+
+	// PadPADME pads to the PADMÉ length of the plaintext, which leaks
+	// O(log log n) bits of its size for at most 12% overhead.
+	PadPADME = Padding{scheme: padPADME}
+
+	// PadPowerOfTwo pads to the next power of two, which leaks O(log log n)
+	// bits of size for up to 100% overhead.
+	PadPowerOfTwo = Padding{scheme: padPowerOfTwo}
+)
+
+// PadBlock pads to a multiple of size bytes.
+func PadBlock(size int) Padding {
+	return Padding{scheme: padBlock, block: int64(size)}
+}
+
+// ParsePadding parses the names returned by Padding.String: "none",
+// "padme", "pow2" or a block size in bytes.
+func ParsePadding(s string) (Padding, error) {
+	switch s {
+	case "", "none":
+		return PadNone, nil
+	case "padme":
+		return PadPADME, nil
+	case "pow2":
+		return PadPowerOfTwo, nil
+	}
+	size, err := strconv.Atoi(s)
+	if err != nil || size < 1 {
+		return PadNone, fmt.Errorf("invalid padding %q", s)
+	}
+	return PadBlock(size), nil
+}
+
+func (p Padding) String() string {
+	switch p.scheme {
+	case padPADME:
+		return "padme"
+	case padPowerOfTwo:
+		return "pow2"
+	case padBlock:
+		return strconv.FormatInt(p.block, 10)
+	}
+	return "none"
+}
+
+// padLength returns how many padding bytes follow n bytes of plaintext. The
+// padding always includes the 0x80 marker.
+func (p Padding) padLength(n int64) int64 {
+	l := n + 1
+	switch p.scheme {
+	case padPADME:
+		e := bits.Len64(uint64(l)) - 1
+		s := bits.Len64(uint64(e))
+		if mask := int64(1)<<max(e-s, 0) - 1; mask > 0 {
+			l = (l + mask) &^ mask
+		}
+	case padPowerOfTwo:
+		if l > 1 {
+			l = int64(1) << bits.Len64(uint64(l-1))
+		}
+	case padBlock:
+		l = (l + p.block - 1) / p.block * p.block
+	}
+	return l - n
+}
+
+// unpadder strips padding from a sequence of decrypted chunks. Padding may
+// span several chunks, so a trailing 0x80 and the zeros after it are held
+// back until a later non-zero byte shows them to be data.
+type unpadder struct {
+	held  bool
+	zeros int
+}
+
+func (u *unpadder) unpad(p []byte, last bool) ([]byte, error) {
+	j := len(p) - 1
+	for j >= 0 && p[j] == 0 {
+		j--
+	}
+
+	var out []byte
+	switch {
+	case j < 0 && u.held:
+		u.zeros += len(p)
+	case j < 0:
+		out = p
+	case u.held:
+		out = make([]byte, 1+u.zeros, 1+u.zeros+len(p))
+		out[0] = 0x80
+		out = append(out, p[:j]...)
+		u.held = false
+	default:
+		out = p[:j]
+	}
+	if j >= 0 {
+		if p[j] == 0x80 {
+			u.held, u.zeros = true, len(p)-j-1
+		} else {
+			out = append(out, p[j:]...)
+		}
+	}
+
+	if last {
+		if !u.held {
+			return nil, corruptf("invalid padding")
+		}
+		u.held = false
+	}
+	return out, nil
+}
@@ -0,0 +1,134 @@
+package argon2aes
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestPadLength(t *testing.T) {
+	testCases := []struct {
+		padding Padding
+		n       int64
+		padded  int64
+	}{
+		{PadNone, 0, 1},
+		{PadNone, 100, 101},
+		{PadPADME, 0, 1},
+		{PadPADME, 8, 10},
+		{PadPADME, 1000, 1024},
+		{PadPADME, 1023, 1024},
+		{PadPADME, 1024, 1088},
+		{PadPADME, 100000, 100352},
+		{PadPowerOfTwo, 0, 1},
+		{PadPowerOfTwo, 1, 2},
+		{PadPowerOfTwo, 1000, 1024},
+		{PadPowerOfTwo, 1024, 2048},
+		{PadBlock(512), 0, 512},
+		{PadBlock(512), 511, 512},
+		{PadBlock(512), 512, 1024},
+	}
+
+	for _, tc := range testCases {
+		if got := tc.n + tc.padding.padLength(tc.n); got != tc.padded {
+			t.Errorf("%v: padded length of %d is %d, want %d", tc.padding, tc.n, got, tc.padded)
+		}
+	}
+}
+
+func TestParsePadding(t *testing.T) {
+	for _, p := range []Padding{PadNone, PadPADME, PadPowerOfTwo, PadBlock(4096)} {
+		parsed, err := ParsePadding(p.String())
+		if err != nil {
+			t.Errorf("ParsePadding(%q) failed: %v", p.String(), err)
+		} else if parsed != p {
+			t.Errorf("ParsePadding(%q) = %v, want %v", p.String(), parsed, p)
+		}
+	}
+
+	for _, s := range []string{"0", "-1", "pad", "1k"} {
+		if _, err := ParsePadding(s); err == nil {
+			t.Errorf("ParsePadding(%q) should return an error", s)
+		}
+	}
+}
+
+func TestPaddingRoundTrip(t *testing.T) {
+	password := []byte("password")
+	marker := append([]byte("data"), 0x80, 0, 0, 0)
+	testCases := []struct {
+		name    string
+		data    []byte
+		padding Padding
+	}{
+		{"Empty", []byte{}, PadPADME},
+		{"Short", []byte("Hello, World!"), PadPADME},
+		{"Zeros", make([]byte, 100), PadPowerOfTwo},
+		{"TrailingMarker", marker, PadPowerOfTwo},
+		{"MarkerAcrossChunks", append(marker, bytes.Repeat([]byte{0}, 200)...), PadBlock(100)},
+		{"MarkerThenData", append(append(marker, make([]byte, 100)...), 1), PadPowerOfTwo},
+		{"ManyChunks", bytes.Repeat([]byte("I"), 1000), PadPowerOfTwo},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			encrypted, err := Encrypt(tc.data, password, WithChunkSize(32), WithPadding(tc.padding))
+			if err != nil {
+				t.Fatalf("Encrypt failed: %v", err)
+			}
+
+			decrypted, err := Decrypt(encrypted, password)
+			if err != nil {
+				t.Fatalf("Decrypt failed: %v", err)
+			}
+			if !bytes.Equal(tc.data, decrypted) {
+				t.Errorf("Decrypted data doesn't match original. Original: %v, Decrypted: %v", tc.data, decrypted)
+			}
+
+			ra, size, err := OpenReaderAt(bytes.NewReader(encrypted), int64(len(encrypted)), password)
+			if err != nil {
+				t.Fatalf("OpenReaderAt failed: %v", err)
+			}
+			if size != int64(len(tc.data)) {
+				t.Fatalf("Expected size %d, got %d", len(tc.data), size)
+			}
+			p := make([]byte, size+10)
+			n, err := ra.ReadAt(p, 0)
+			if err != io.EOF || !bytes.Equal(tc.data, p[:n]) {
+				t.Errorf("ReadAt returned %v, %v", p[:n], err)
+			}
+		})
+	}
+}
+
+func TestPaddingHidesLength(t *testing.T) {
+	password := []byte("password")
+
+	short, err := Encrypt([]byte("a"), password, WithPadding(PadBlock(1024)))
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	long, err := Encrypt(bytes.Repeat([]byte("a"), 1000), password, WithPadding(PadBlock(1024)))
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if len(short) != len(long) {
+		t.Errorf("Padded ciphertexts differ in length: %d and %d", len(short), len(long))
+	}
+}
+
+func TestUnpadInvalid(t *testing.T) {
+	testCases := [][]byte{
+		{},
+		{1, 2, 3},
+		{0x80, 1},
+		{0, 0},
+	}
+
+	for _, tc := range testCases {
+		u := &unpadder{}
+		if _, err := u.unpad(tc, true); err == nil {
+			t.Errorf("unpad(%v) should return an error", tc)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package argon2aes
+
+import "sync"
+
+// parallel calls fn for every i in [0, n) using at most jobs goroutines and
+// returns the error for the lowest failing i.
+func parallel(n, jobs int, fn func(i int) error) error {
+	if jobs <= 1 || n <= 1 {
+		for i := 0; i < n; i++ {
+			if err := fn(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for w := 0; w < min(jobs, n); w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < n; i += jobs {
+				errs[i] = fn(i)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
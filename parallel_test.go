@@ -0,0 +1,123 @@
+package argon2aes
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestParallelRoundTrip(t *testing.T) {
+	password := []byte("password")
+	data := make([]byte, 10*1024+100)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, jobs := range []int{1, 3, 8} {
+		t.Run(fmt.Sprintf("jobs=%d", jobs), func(t *testing.T) {
+			encrypted := encryptStream(t, data, password, WithChunkSize(1024), WithJobs(jobs))
+			if len(encrypted) != headerLength+len(data)+11*tagLength {
+				t.Errorf("Unexpected stream length %d", len(encrypted))
+			}
+
+			// Decrypt with a different job count to show the output does
+			// not depend on it.
+			r, err := NewReader(bytes.NewReader(encrypted), password, WithJobs(4-jobs%4))
+			if err != nil {
+				t.Fatalf("NewReader failed: %v", err)
+			}
+			decrypted, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll failed: %v", err)
+			}
+			if !bytes.Equal(data, decrypted) {
+				t.Error("Decrypted data doesn't match original")
+			}
+		})
+	}
+}
+
+func TestParallelCorruptChunk(t *testing.T) {
+	password := []byte("password")
+	data := bytes.Repeat([]byte("G"), 4096)
+	encrypted := encryptStream(t, data, password, WithChunkSize(256))
+	encrypted[headerLength+5*(256+tagLength)+1]++
+
+	r, err := NewReader(bytes.NewReader(encrypted), password, WithJobs(4))
+	if err != nil {
+		t.Fatalf("NewReader failed: %v", err)
+	}
+	if _, err := io.ReadAll(r); err == nil {
+		t.Error("Expected an error when decrypting a corrupt chunk, but got none")
+	}
+}
+
+func TestParallelErrorOrder(t *testing.T) {
+	err := parallel(10, 4, func(i int) error {
+		if i == 3 || i == 7 {
+			return fmt.Errorf("error %d", i)
+		}
+		return nil
+	})
+	if err == nil || err.Error() != "error 3" {
+		t.Errorf("Expected the error of the lowest index, got %v", err)
+	}
+}
+
+func benchmarkKeys(b *testing.B) (*header, *streamKeys) {
+	h := &header{chunkSize: DefaultChunkSize, time: time, memory: memory, threads: threads}
+	keys, err := deriveStreamKeys(context.Background(), h, []byte("password"), newOptions(nil))
+	if err != nil {
+		b.Fatal(err)
+	}
+	return h, keys
+}
+
+func BenchmarkWriter(b *testing.B) {
+	_, keys := benchmarkKeys(b)
+	data := make([]byte, 4*1024*1024)
+
+	for _, jobs := range []int{1, 2, 4, 8, 16} {
+		b.Run(fmt.Sprintf("jobs=%d", jobs), func(b *testing.B) {
+			w := newStreamWriter(context.Background(), io.Discard, keys, newOptions([]Option{WithJobs(jobs)}))
+			b.SetBytes(int64(len(data)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := w.Write(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				b.Fatal(err)
+			}
+		})
+	}
+}
+
+func BenchmarkReader(b *testing.B) {
+	h, keys := benchmarkKeys(b)
+	data := make([]byte, 4*1024*1024)
+
+	var buf bytes.Buffer
+	w := newStreamWriter(context.Background(), &buf, keys, newOptions(nil))
+	w.Write(data)
+	w.Close()
+	payload := buf.Bytes()
+
+	for _, jobs := range []int{1, 2, 4, 8, 16} {
+		b.Run(fmt.Sprintf("jobs=%d", jobs), func(b *testing.B) {
+			o := newOptions([]Option{WithJobs(jobs)})
+			b.SetBytes(int64(len(data)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				r := newStreamReader(context.Background(), bytes.NewReader(payload), h, keys, o)
+				if _, err := io.Copy(io.Discard, r); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
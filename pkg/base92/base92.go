@@ -1,24 +1,78 @@
+// Package base92 implements base92 encoding, which writes binary data with
+// 92 of the 94 printable ASCII characters.
+//
+// Every byte string has exactly one encoding, and decoders reject any other
+// input, so two encodings decode to the same bytes only if they are equal:
+//
+//   - The block encoding (StdEncoding) writes each group of 13 bytes as 16
+//     symbols and a final group of k bytes as the fewest symbols that hold
+//     256^k values. A group of another length, or one whose value does not
+//     fit in its bytes, is invalid.
+//   - The legacy encoding (DefaultEncoding) writes each leading zero byte
+//     as one zero symbol, the first character of the alphabet, followed by
+//     the rest of the data as a base92 number without leading zeros. Any
+//     string of the alphabet is valid: its leading zero symbols are the
+//     leading zero bytes, and the rest is a number with a nonzero first
+//     digit.
+//   - Neither writes whitespace, padding or line breaks. Decoders reject
+//     them unless made lenient with IgnoreWhitespace.
+//
+// The file testdata/vectors.json holds test vectors for other
+// implementations.
 package base92
 
 import (
 	"bytes"
-	"errors"
 	"fmt"
 	"math/big"
+	"strconv"
 )
 
 const alphabet = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ.-:+=^!/*?&<>()[]{}@%$#|;,_~`'"
 
-var DefaultEncoding = NewEncoding(alphabet)
+// shellAlphabet is alphabet with the single quote replaced by a double quote.
+const shellAlphabet = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ.-:+=^!/*?&<>()[]{}@%$#|;,_~`\""
+
+// StdEncoding encodes each 13 bytes as 16 symbols of the standard
+// alphabet, in time linear in the input.
+var StdEncoding = mustNewEncoding(alphabet)
+
+// DefaultEncoding is the original encoding of the standard alphabet, which
+// converts the whole input to a single number. It takes quadratic time and
+// is kept for reading and writing existing data; use StdEncoding for new
+// data.
+var DefaultEncoding = StdEncoding.Legacy()
+
+// ShellEncoding is a block encoding whose alphabet has no single quote or
+// backslash, so its output can be pasted between single quotes in a POSIX
+// shell or YAML.
+var ShellEncoding = mustNewEncoding(shellAlphabet)
+
+// JSONEncoding is a block encoding whose alphabet has no double quote or
+// backslash, so its output needs no escaping in a JSON string. The standard
+// alphabet already leaves them out, so it is the same as StdEncoding.
+var JSONEncoding = StdEncoding
 
 type Encoding struct {
-	encode    [92]byte
-	decodeMap [256]byte
+	encode      [92]byte
+	decodeMap   [256]byte
+	legacy      bool
+	ignoreSpace bool
 }
 
-func NewEncoding(encoder string) *Encoding {
+// CorruptInputError is the offset in the input of the first byte that
+// could not be decoded.
+type CorruptInputError int64
+
+func (e CorruptInputError) Error() string {
+	return "illegal base92 data at input byte " + strconv.FormatInt(int64(e), 10)
+}
+
+// NewEncoding returns a block encoding using the 92 characters of encoder,
+// which must be distinct printable ASCII characters other than space.
+func NewEncoding(encoder string) (*Encoding, error) {
 	if len(encoder) != 92 {
-		panic("encoding alphabet is not 92-bytes long: " + fmt.Sprint(len(encoder)))
+		return nil, fmt.Errorf("base92 alphabet has %d characters, must have 92", len(encoder))
 	}
 
 	e := new(Encoding)
@@ -28,12 +82,80 @@ func NewEncoding(encoder string) *Encoding {
 		e.decodeMap[i] = 0xFF
 	}
 	for i := 0; i < len(encoder); i++ {
-		e.decodeMap[encoder[i]] = byte(i)
+		c := encoder[i]
+		if c <= ' ' || c > '~' {
+			return nil, fmt.Errorf("base92 alphabet contains %q, which is not printable ASCII", c)
+		}
+		if e.decodeMap[c] != 0xFF {
+			return nil, fmt.Errorf("base92 alphabet contains %q more than once", c)
+		}
+		e.decodeMap[c] = byte(i)
+	}
+	return e, nil
+}
+
+// mustNewEncoding is NewEncoding for the alphabets of this package, which
+// are known to be valid.
+func mustNewEncoding(encoder string) *Encoding {
+	e, err := NewEncoding(encoder)
+	if err != nil {
+		panic(err)
 	}
 	return e
 }
 
+// Legacy returns an encoding with the same alphabet as enc that converts
+// the whole input to a single number, like DefaultEncoding.
+func (enc Encoding) Legacy() *Encoding {
+	enc.legacy = true
+	return &enc
+}
+
+// IgnoreWhitespace returns an encoding like enc whose decoders skip spaces,
+// tabs and line breaks, such as those added when the encoding is wrapped or
+// pasted.
+func (enc Encoding) IgnoreWhitespace() *Encoding {
+	enc.ignoreSpace = true
+	return &enc
+}
+
+// Strict returns an encoding like enc whose decoders accept only the
+// encoding that Encode writes, undoing IgnoreWhitespace. The encodings of
+// this package are strict.
+func (enc Encoding) Strict() *Encoding {
+	enc.ignoreSpace = false
+	return &enc
+}
+
+// isSpace reports whether c is ASCII whitespace.
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\v' || c == '\f' || c == '\r'
+}
+
+// EncodeToString returns the encoding of src.
 func (enc *Encoding) EncodeToString(src []byte) string {
+	if enc.legacy {
+		return enc.encodeLegacy(src)
+	}
+	dst := make([]byte, enc.EncodedLen(len(src)))
+	enc.Encode(dst, src)
+	return string(dst)
+}
+
+// DecodeString returns the bytes represented by s.
+func (enc *Encoding) DecodeString(s string) ([]byte, error) {
+	if enc.legacy {
+		return enc.decodeLegacy(s)
+	}
+	dst := make([]byte, enc.DecodedLen(len(s)))
+	n, err := enc.Decode(dst, []byte(s))
+	if err != nil {
+		return nil, err
+	}
+	return dst[:n], nil
+}
+
+func (enc *Encoding) encodeLegacy(src []byte) string {
 	if len(src) == 0 {
 		return ""
 	}
@@ -72,7 +194,7 @@ func (enc *Encoding) EncodeToString(src []byte) string {
 	return string(encoded)
 }
 
-func (enc *Encoding) DecodeString(s string) ([]byte, error) {
+func (enc *Encoding) decodeLegacy(s string) ([]byte, error) {
 	if len(s) == 0 {
 		return []byte{}, nil
 	}
@@ -81,10 +203,13 @@ func (enc *Encoding) DecodeString(s string) ([]byte, error) {
 	base := big.NewInt(92)
 	leadingZeros := 0
 
-	for _, c := range s {
-		index := enc.decodeMap[c]
+	for i := 0; i < len(s); i++ {
+		if enc.ignoreSpace && isSpace(s[i]) {
+			continue
+		}
+		index := enc.decodeMap[s[i]]
 		if index == 0xFF {
-			return nil, errors.New("invalid base92 character")
+			return nil, CorruptInputError(i)
 		}
 		x.Mul(x, base)
 		x.Add(x, big.NewInt(int64(index)))
@@ -2,7 +2,15 @@ package base92
 
 import (
 	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestBase92(t *testing.T) {
@@ -72,3 +80,443 @@ func TestRoundTrip(t *testing.T) {
 		}
 	}
 }
+
+func TestStdEncoding(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    []byte
+		expected string
+	}{
+		{"Empty", []byte{}, ""},
+		{"Single byte", []byte{0}, "00"},
+		{"Hello World", []byte("Hello World"), "2S4n*AHcqRHp?g"},
+		{"Binary data", []byte{0xFF, 0x00, 0xAA, 0x55}, "X=eA5"},
+		{"Full block", bytes.Repeat([]byte{0xFF}, 13), "*[Wt[ve3&sCI5<Yv"},
+		{"Long text", []byte("The quick brown fox jumps over the lazy dog"), "nw`l,ry'dph:nCY-x4Wr4tL8Z_9~U~%ou[?/|%GZ@=bBijCx7R$Sf"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			encoded := StdEncoding.EncodeToString(tc.input)
+			if encoded != tc.expected {
+				t.Errorf("EncodeToString(%v) = %+v, want %+v", tc.input, encoded, tc.expected)
+			}
+
+			decoded, err := StdEncoding.DecodeString(tc.expected)
+			if err != nil {
+				t.Errorf("DecodeString(%s) returned error: %v", tc.expected, err)
+			}
+			if !bytes.Equal(decoded, tc.input) {
+				t.Errorf("DecodeString(%s) = %v, want %v", tc.expected, decoded, tc.input)
+			}
+		})
+	}
+}
+
+func TestStdEncodingRoundTrip(t *testing.T) {
+	data := make([]byte, 100)
+	for i := range data {
+		data[i] = byte(i*131 + 7)
+	}
+
+	for n := 0; n <= len(data); n++ {
+		encoded := StdEncoding.EncodeToString(data[:n])
+		if want := n/13*16 + tailSymbols[n%13]; len(encoded) != want {
+			t.Errorf("Encoding %d bytes gave %d symbols, want %d", n, len(encoded), want)
+		}
+		decoded, err := StdEncoding.DecodeString(encoded)
+		if err != nil {
+			t.Fatalf("DecodeString of %d bytes returned error: %v", n, err)
+		}
+		if !bytes.Equal(decoded, data[:n]) {
+			t.Errorf("Round trip of %d bytes failed. Got %v", n, decoded)
+		}
+	}
+}
+
+func TestStdEncodingInvalid(t *testing.T) {
+	invalidInputs := []string{
+		"0",                 // no group has one symbol
+		"000000",            // or six
+		"~~",                // 8463 does not fit in one byte
+		"~~~~~~~~~~~~~~~~",  // 92^16-1 does not fit in 13 bytes
+		"0000000000000000~", // nor does a tail with one symbol
+		"invalid char £",
+	}
+
+	for _, input := range invalidInputs {
+		t.Run(input, func(t *testing.T) {
+			if _, err := StdEncoding.DecodeString(input); err == nil {
+				t.Errorf("DecodeString(%s) should return an error", input)
+			}
+		})
+	}
+}
+
+func TestCorruptInputError(t *testing.T) {
+	testCases := []struct {
+		name   string
+		enc    *Encoding
+		input  string
+		offset int64
+	}{
+		{"ShortTail", StdEncoding, "0", 0},
+		{"LongTail", StdEncoding, "0000000000000000~", 16},
+		{"Overflow", StdEncoding, "~~", 0},
+		{"NonASCII", StdEncoding, "00£", 2},
+		{"Newline", StdEncoding, "00\n00", 2},
+		{"LegacyWideRune", DefaultEncoding, "ab日本", 2},
+		{"LegacyNewline", DefaultEncoding, "abc\n", 3},
+		{"IgnoreWhitespaceOverflow", StdEncoding.IgnoreWhitespace(), " \n~~", 2},
+		{"IgnoreWhitespaceNonASCII", StdEncoding.IgnoreWhitespace(), "0\r\n0£", 4},
+		{"LegacyIgnoreWhitespace", DefaultEncoding.IgnoreWhitespace(), "ab\tc£", 4},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.enc.DecodeString(tc.input)
+			var corrupt CorruptInputError
+			if !errors.As(err, &corrupt) || int64(corrupt) != tc.offset {
+				t.Errorf("Expected CorruptInputError(%d), got %v", tc.offset, err)
+			}
+
+			r := NewDecoder(tc.enc, iotest.OneByteReader(strings.NewReader(tc.input)))
+			if _, err := io.ReadAll(r); !errors.As(err, &corrupt) || int64(corrupt) != tc.offset {
+				t.Errorf("Expected the decoder to return CorruptInputError(%d), got %v", tc.offset, err)
+			}
+		})
+	}
+}
+
+func TestIgnoreWhitespace(t *testing.T) {
+	data := benchmarkData(200)
+
+	for _, enc := range []*Encoding{StdEncoding, DefaultEncoding} {
+		encoded := enc.EncodeToString(data)
+		var wrapped strings.Builder
+		for i := 0; i < len(encoded); i += 64 {
+			wrapped.WriteString(encoded[i:min(i+64, len(encoded))])
+			wrapped.WriteString("\r\n")
+		}
+		input := " \t" + wrapped.String()
+
+		if _, err := enc.DecodeString(input); err == nil {
+			t.Errorf("Expected an error decoding wrapped input (legacy %v), but got none", enc.legacy)
+		}
+		decoded, err := enc.IgnoreWhitespace().DecodeString(input)
+		if err != nil || !bytes.Equal(decoded, data) {
+			t.Errorf("Decoding wrapped input (legacy %v) gave %v, %v", enc.legacy, decoded, err)
+		}
+		r := NewDecoder(enc.IgnoreWhitespace(), iotest.HalfReader(strings.NewReader(input)))
+		if decoded, err := io.ReadAll(r); err != nil || !bytes.Equal(decoded, data) {
+			t.Errorf("Streaming wrapped input (legacy %v) gave %v, %v", enc.legacy, decoded, err)
+		}
+	}
+}
+
+func TestNewEncoding(t *testing.T) {
+	testCases := []struct {
+		name     string
+		alphabet string
+	}{
+		{"Short", alphabet[1:]},
+		{"Long", alphabet + "\\"},
+		{"Duplicate", alphabet[:91] + "0"},
+		{"Space", alphabet[:91] + " "},
+		{"Control", alphabet[:91] + "\n"},
+		{"NonASCII", alphabet[:90] + "£"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewEncoding(tc.alphabet); err == nil {
+				t.Errorf("Expected an error for alphabet %q, but got none", tc.alphabet)
+			}
+		})
+	}
+
+	enc, err := NewEncoding(alphabet[1:] + "\\")
+	if err != nil {
+		t.Fatalf("NewEncoding failed: %v", err)
+	}
+	data := benchmarkData(100)
+	if decoded, err := enc.DecodeString(enc.EncodeToString(data)); err != nil || !bytes.Equal(decoded, data) {
+		t.Errorf("Round trip with a custom alphabet gave %v, %v", decoded, err)
+	}
+}
+
+func TestSafeEncodings(t *testing.T) {
+	testCases := []struct {
+		name   string
+		enc    *Encoding
+		unsafe string
+	}{
+		{"Shell", ShellEncoding, "'\\"},
+		{"JSON", JSONEncoding, "\"\\"},
+	}
+
+	data := benchmarkData(1000)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if strings.ContainsAny(string(tc.enc.encode[:]), tc.unsafe) {
+				t.Errorf("Alphabet %q contains one of %q", tc.enc.encode, tc.unsafe)
+			}
+			decoded, err := tc.enc.DecodeString(tc.enc.EncodeToString(data))
+			if err != nil || !bytes.Equal(decoded, data) {
+				t.Errorf("Round trip gave %v, %v", decoded, err)
+			}
+		})
+	}
+}
+
+func TestLegacy(t *testing.T) {
+	enc, err := NewEncoding(alphabet)
+	if err != nil {
+		t.Fatalf("NewEncoding failed: %v", err)
+	}
+	legacy := enc.Legacy()
+	if enc.legacy || !legacy.legacy || !DefaultEncoding.legacy {
+		t.Fatal("Legacy must return a legacy copy and leave the receiver unchanged")
+	}
+	if got := legacy.EncodeToString([]byte{0}); got != "0" {
+		t.Errorf("Legacy EncodeToString([0]) = %q, want %q", got, "0")
+	}
+}
+
+// vectors is the content of testdata/vectors.json.
+type vectors struct {
+	Alphabet string
+	Valid    []struct {
+		Encoding string
+		Hex      string
+		Encoded  string
+	}
+	Invalid []struct {
+		Encoding string
+		Encoded  string
+		Offset   int64
+	}
+}
+
+func loadVectors(tb testing.TB) vectors {
+	data, err := os.ReadFile("testdata/vectors.json")
+	if err != nil {
+		tb.Fatalf("Failed to read test vectors: %v", err)
+	}
+	var v vectors
+	if err := json.Unmarshal(data, &v); err != nil {
+		tb.Fatalf("Failed to parse test vectors: %v", err)
+	}
+	return v
+}
+
+// vectorEncoding returns the encoding named in the test vectors.
+func vectorEncoding(tb testing.TB, name string) *Encoding {
+	switch name {
+	case "block":
+		return StdEncoding
+	case "legacy":
+		return DefaultEncoding
+	}
+	tb.Fatalf("Unknown encoding %q in test vectors", name)
+	return nil
+}
+
+func TestVectors(t *testing.T) {
+	v := loadVectors(t)
+	if v.Alphabet != alphabet {
+		t.Fatalf("Test vectors use alphabet %q", v.Alphabet)
+	}
+
+	for _, tc := range v.Valid {
+		t.Run(tc.Encoding+"/"+tc.Encoded, func(t *testing.T) {
+			enc := vectorEncoding(t, tc.Encoding)
+			data, err := hex.DecodeString(tc.Hex)
+			if err != nil {
+				t.Fatalf("Invalid hex %q: %v", tc.Hex, err)
+			}
+			if got := enc.EncodeToString(data); got != tc.Encoded {
+				t.Errorf("EncodeToString(%x) = %q, want %q", data, got, tc.Encoded)
+			}
+			if got, err := enc.DecodeString(tc.Encoded); err != nil || !bytes.Equal(got, data) {
+				t.Errorf("DecodeString(%q) = %x, %v, want %x", tc.Encoded, got, err, data)
+			}
+		})
+	}
+
+	for _, tc := range v.Invalid {
+		t.Run(tc.Encoding+"/"+tc.Encoded, func(t *testing.T) {
+			enc := vectorEncoding(t, tc.Encoding)
+			var corrupt CorruptInputError
+			if _, err := enc.DecodeString(tc.Encoded); !errors.As(err, &corrupt) || int64(corrupt) != tc.Offset {
+				t.Errorf("Expected CorruptInputError(%d), got %v", tc.Offset, err)
+			}
+		})
+	}
+}
+
+// TestCanonical checks every short input: any that decodes must be the
+// encoding of what it decodes to.
+func TestCanonical(t *testing.T) {
+	var inputs []string
+	var extend func(prefix string, n int)
+	extend = func(prefix string, n int) {
+		inputs = append(inputs, prefix)
+		if n == 0 {
+			return
+		}
+		for i := 0; i < len(alphabet); i++ {
+			extend(prefix+alphabet[i:i+1], n-1)
+		}
+	}
+	extend("", 3)
+
+	for _, enc := range []*Encoding{StdEncoding, DefaultEncoding} {
+		valid := 0
+		for _, s := range inputs {
+			data, err := enc.DecodeString(s)
+			if err != nil {
+				continue
+			}
+			valid++
+			if got := enc.EncodeToString(data); got != s {
+				t.Fatalf("%q decodes to %x (legacy %v), which encodes to %q", s, data, enc.legacy, got)
+			}
+		}
+		if enc.legacy && valid != len(inputs) {
+			t.Errorf("Only %d of %d legacy inputs decode", valid, len(inputs))
+		}
+	}
+}
+
+func TestStrict(t *testing.T) {
+	for _, enc := range []*Encoding{StdEncoding, DefaultEncoding} {
+		lenient := enc.IgnoreWhitespace()
+		strict := lenient.Strict()
+		if _, err := lenient.DecodeString("00 0"); err != nil {
+			t.Errorf("Expected lenient decoding to skip a space (legacy %v), got %v", enc.legacy, err)
+		}
+		if _, err := strict.DecodeString("00 0"); err == nil {
+			t.Errorf("Expected an error decoding a space strictly (legacy %v), but got none", enc.legacy)
+		}
+		if !lenient.ignoreSpace || strict.ignoreSpace || strict.legacy != enc.legacy {
+			t.Errorf("Strict must return a strict copy and leave the receiver unchanged")
+		}
+	}
+}
+
+func FuzzRoundTrip(f *testing.F) {
+	for _, tc := range loadVectors(f).Valid {
+		data, _ := hex.DecodeString(tc.Hex)
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, enc := range []*Encoding{StdEncoding, DefaultEncoding, ShellEncoding} {
+			if enc.legacy && len(data) > 1024 {
+				continue // quadratic
+			}
+			encoded := enc.EncodeToString(data)
+			if len(encoded) > enc.EncodedLen(len(data)) {
+				t.Fatalf("Encoding of %d bytes (legacy %v) has %d bytes, more than EncodedLen", len(data), enc.legacy, len(encoded))
+			}
+			decoded, err := enc.DecodeString(encoded)
+			if err != nil || !bytes.Equal(decoded, data) {
+				t.Fatalf("Round trip of %x (legacy %v) gave %x, %v", data, enc.legacy, decoded, err)
+			}
+
+			var b strings.Builder
+			w := NewEncoder(enc, &b)
+			w.Write(data)
+			w.Close()
+			if b.String() != encoded {
+				t.Fatalf("Encoder wrote %q (legacy %v), want %q", b.String(), enc.legacy, encoded)
+			}
+		}
+	})
+}
+
+func FuzzDecode(f *testing.F) {
+	v := loadVectors(f)
+	for _, tc := range v.Valid {
+		f.Add(tc.Encoded)
+	}
+	for _, tc := range v.Invalid {
+		f.Add(tc.Encoded)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		for _, enc := range []*Encoding{StdEncoding, DefaultEncoding} {
+			if enc.legacy && len(s) > 1024 {
+				continue // quadratic
+			}
+			data, err := enc.DecodeString(s)
+			if err == nil && enc.EncodeToString(data) != s {
+				t.Fatalf("%q decodes to %x (legacy %v), which encodes otherwise", s, data, enc.legacy)
+			}
+			if err == nil && len(data) > enc.DecodedLen(len(s)) {
+				t.Fatalf("%q decodes to %d bytes (legacy %v), more than DecodedLen", s, len(data), enc.legacy)
+			}
+			streamed, streamErr := io.ReadAll(NewDecoder(enc, iotest.HalfReader(strings.NewReader(s))))
+			if (err == nil) != (streamErr == nil) || err == nil && !bytes.Equal(streamed, data) {
+				t.Fatalf("Decoder of %q (legacy %v) gave %x, %v; DecodeString gave %x, %v", s, enc.legacy, streamed, streamErr, data, err)
+			}
+
+			// Whitespace only changes the offsets of errors.
+			var trimmed []byte
+			for i := 0; i < len(s); i++ {
+				if !isSpace(s[i]) {
+					trimmed = append(trimmed, s[i])
+				}
+			}
+			lenient, lenientErr := enc.IgnoreWhitespace().DecodeString(s)
+			want, wantErr := enc.DecodeString(string(trimmed))
+			if (lenientErr == nil) != (wantErr == nil) || !bytes.Equal(lenient, want) {
+				t.Fatalf("Lenient decoding of %q (legacy %v) gave %x, %v, want %x, %v", s, enc.legacy, lenient, lenientErr, want, wantErr)
+			}
+		}
+	})
+}
+
+func benchmarkData(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i*131 + 7)
+	}
+	return data
+}
+
+func BenchmarkEncode(b *testing.B) {
+	for _, size := range []int{1 << 10, 4 << 10, 16 << 10} {
+		data := benchmarkData(size)
+		for _, enc := range []struct {
+			name string
+			enc  *Encoding
+		}{{"Block", StdEncoding}, {"Legacy", DefaultEncoding}} {
+			b.Run(fmt.Sprintf("%s/%dKiB", enc.name, size>>10), func(b *testing.B) {
+				b.SetBytes(int64(size))
+				for i := 0; i < b.N; i++ {
+					enc.enc.EncodeToString(data)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	for _, size := range []int{1 << 10, 4 << 10, 16 << 10} {
+		data := benchmarkData(size)
+		for _, enc := range []struct {
+			name string
+			enc  *Encoding
+		}{{"Block", StdEncoding}, {"Legacy", DefaultEncoding}} {
+			encoded := enc.enc.EncodeToString(data)
+			b.Run(fmt.Sprintf("%s/%dKiB", enc.name, size>>10), func(b *testing.B) {
+				b.SetBytes(int64(size))
+				for i := 0; i < b.N; i++ {
+					if _, err := enc.enc.DecodeString(encoded); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
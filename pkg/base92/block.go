@@ -0,0 +1,179 @@
+package base92
+
+import "math/bits"
+
+// The block encoding splits the input into groups of 13 bytes, each read
+// as a big-endian number and written as 16 base92 digits, most significant
+// first. 92^16 is just over 2^104, so no group needs more. A shorter final
+// group of k bytes takes the fewest digits that can hold 256^k values.
+const (
+	blockBytes   = 13
+	blockSymbols = 16
+)
+
+// tailSymbols[k] is the number of symbols for a final group of k bytes.
+var tailSymbols = [blockBytes]int{0, 2, 3, 4, 5, 7, 8, 9, 10, 12, 13, 14, 15}
+
+// tailBytes[n] is the number of bytes in a final group of n symbols, or -1
+// if no group has n symbols.
+var tailBytes = func() [blockSymbols]int {
+	var t [blockSymbols]int
+	for i := range t {
+		t[i] = -1
+	}
+	for k, n := range tailSymbols {
+		t[n] = k
+	}
+	return t
+}()
+
+// EncodedLen returns the length in bytes of the encoding of n bytes. For a
+// legacy encoding, whose length depends on the data, it is the maximum.
+func (enc *Encoding) EncodedLen(n int) int {
+	if enc.legacy {
+		// 8 / log2(92) < 1.2264
+		return (n*12264 + 9999) / 10000
+	}
+	return n/blockBytes*blockSymbols + tailSymbols[n%blockBytes]
+}
+
+// DecodedLen returns the maximum length in bytes of the data decoded from
+// n bytes of encoding.
+func (enc *Encoding) DecodedLen(n int) int {
+	if enc.legacy {
+		return n
+	}
+	k := 0
+	for k+1 < blockBytes && tailSymbols[k+1] <= n%blockSymbols {
+		k++
+	}
+	return n/blockSymbols*blockBytes + k
+}
+
+// Encode encodes src into dst, which must have room for
+// EncodedLen(len(src)) bytes, and returns the number of bytes written.
+func (enc *Encoding) Encode(dst, src []byte) int {
+	if enc.legacy {
+		return copy(dst, enc.encodeLegacy(src))
+	}
+	j := 0
+	for i := 0; i < len(src); {
+		k := min(len(src)-i, blockBytes)
+		n := blockSymbols
+		if k < blockBytes {
+			n = tailSymbols[k]
+		}
+		enc.encodeBlock(dst[j:j+n], src[i:i+k])
+		i, j = i+k, j+n
+	}
+	return j
+}
+
+// encodeBlock writes the group src as len(dst) digits.
+func (enc *Encoding) encodeBlock(dst, src []byte) {
+	var hi, lo uint64
+	for _, b := range src {
+		hi = hi<<8 | lo>>56
+		lo = lo<<8 | uint64(b)
+	}
+	for i := len(dst) - 1; i >= 0; i-- {
+		var r uint64
+		hi, r = hi/92, hi%92
+		lo, r = bits.Div64(r, lo, 92)
+		dst[i] = enc.encode[r]
+	}
+}
+
+// Decode decodes src into dst, which must have room for
+// DecodedLen(len(src)) bytes, and returns the number of bytes written. An
+// invalid input byte, or a group that no data encodes to, is reported as a
+// CorruptInputError.
+func (enc *Encoding) Decode(dst, src []byte) (int, error) {
+	if enc.legacy {
+		decoded, err := enc.decodeLegacy(string(src))
+		return copy(dst, decoded), err
+	}
+	g := groupDecoder{enc: enc}
+	n, err := g.decode(dst, src)
+	if err != nil {
+		return n, err
+	}
+	m, err := g.finish(dst[n:])
+	return n + m, err
+}
+
+// groupDecoder decodes input that may arrive in pieces, keeping the digits
+// of a partial group between calls.
+type groupDecoder struct {
+	enc    *Encoding
+	digits [blockSymbols]byte
+	n      int   // number of digits held
+	start  int64 // offset of the first digit held
+	offset int64 // offset of the next input byte
+}
+
+// decode decodes the complete groups of src, after any digits held, into
+// dst. It holds back the digits of a final partial group, which can only be
+// decoded once the input is known to end.
+func (g *groupDecoder) decode(dst, src []byte) (int, error) {
+	j := 0
+	for i, c := range src {
+		if g.enc.ignoreSpace && isSpace(c) {
+			continue
+		}
+		d := g.enc.decodeMap[c]
+		if d == 0xFF {
+			return j, CorruptInputError(g.offset + int64(i))
+		}
+		if g.n == 0 {
+			g.start = g.offset + int64(i)
+		}
+		g.digits[g.n] = d
+		g.n++
+		if g.n == blockSymbols {
+			if !decodeBlock(dst[j:j+blockBytes], g.digits[:]) {
+				return j, CorruptInputError(g.start)
+			}
+			j += blockBytes
+			g.n = 0
+		}
+	}
+	g.offset += int64(len(src))
+	return j, nil
+}
+
+// finish decodes the final partial group, if any.
+func (g *groupDecoder) finish(dst []byte) (int, error) {
+	if g.n == 0 {
+		return 0, nil
+	}
+	k := tailBytes[g.n]
+	if k < 0 || !decodeBlock(dst[:k], g.digits[:g.n]) {
+		return 0, CorruptInputError(g.start)
+	}
+	g.n = 0
+	return k, nil
+}
+
+// decodeBlock reads the digits of one group into dst. It reports false if
+// their value does not fit in len(dst) bytes.
+func decodeBlock(dst, digits []byte) bool {
+	var hi, lo uint64
+	for _, d := range digits {
+		h, l := bits.Mul64(lo, 92)
+		var carry uint64
+		lo, carry = bits.Add64(l, uint64(d), 0)
+		hi = hi*92 + h + carry
+	}
+
+	if len(dst) < 8 && (hi != 0 || lo>>(8*len(dst)) != 0) ||
+		len(dst) >= 8 && hi>>(8*(len(dst)-8)) != 0 {
+		return false
+	}
+	for i := len(dst) - 1; i >= 0; i-- {
+		dst[i] = byte(lo)
+		lo = lo>>8 | hi<<56
+		hi >>= 8
+	}
+	return true
+}
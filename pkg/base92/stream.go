@@ -0,0 +1,135 @@
+package base92
+
+import (
+	"bytes"
+	"io"
+)
+
+// NewEncoder returns an encoder that writes the encoding of the bytes
+// written to it to w. It writes complete 13-byte groups as they fill, and
+// the final partial group on Close, which must be called to flush it. A
+// legacy encoding can only be computed once all input is known, so its
+// encoder buffers everything until Close.
+func NewEncoder(enc *Encoding, w io.Writer) io.WriteCloser {
+	return &encoder{enc: enc, w: w}
+}
+
+type encoder struct {
+	enc  *Encoding
+	w    io.Writer
+	err  error
+	buf  [blockBytes]byte // partial group
+	nbuf int
+	out  [64 * blockSymbols]byte
+	all  bytes.Buffer // the whole input of a legacy encoding
+}
+
+func (e *encoder) Write(p []byte) (n int, err error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	if e.enc.legacy {
+		return e.all.Write(p)
+	}
+
+	if e.nbuf > 0 {
+		i := copy(e.buf[e.nbuf:], p)
+		e.nbuf += i
+		n, p = i, p[i:]
+		if e.nbuf < blockBytes {
+			return n, nil
+		}
+		m := e.enc.Encode(e.out[:], e.buf[:])
+		if _, e.err = e.w.Write(e.out[:m]); e.err != nil {
+			return n, e.err
+		}
+		e.nbuf = 0
+	}
+
+	for len(p) >= blockBytes {
+		k := min(len(p)/blockBytes, len(e.out)/blockSymbols) * blockBytes
+		m := e.enc.Encode(e.out[:], p[:k])
+		if _, e.err = e.w.Write(e.out[:m]); e.err != nil {
+			return n, e.err
+		}
+		n, p = n+k, p[k:]
+	}
+
+	e.nbuf = copy(e.buf[:], p)
+	return n + e.nbuf, nil
+}
+
+// Close flushes any pending output. It does not close the underlying
+// writer.
+func (e *encoder) Close() error {
+	if e.err != nil {
+		return e.err
+	}
+	if e.enc.legacy {
+		_, e.err = io.WriteString(e.w, e.enc.EncodeToString(e.all.Bytes()))
+		e.all.Reset()
+		return e.err
+	}
+	if e.nbuf > 0 {
+		m := e.enc.Encode(e.out[:], e.buf[:e.nbuf])
+		e.nbuf = 0
+		_, e.err = e.w.Write(e.out[:m])
+	}
+	return e.err
+}
+
+// NewDecoder returns a decoder that reads the encoding of data from r and
+// returns the data. Like its encoder, the decoder of a legacy encoding reads
+// all of r before returning anything.
+func NewDecoder(enc *Encoding, r io.Reader) io.Reader {
+	return &decoder{enc: enc, r: r, g: groupDecoder{enc: enc}}
+}
+
+type decoder struct {
+	enc    *Encoding
+	r      io.Reader
+	g      groupDecoder
+	err    error // returned once out is empty
+	buf    [64 * blockSymbols]byte
+	outbuf [65 * blockBytes]byte // room for buf and a held partial group
+	out    []byte                // decoded but not yet returned
+}
+
+func (d *decoder) Read(p []byte) (int, error) {
+	for len(d.out) == 0 && d.err == nil {
+		if d.enc.legacy {
+			d.readLegacy()
+			continue
+		}
+		n, readErr := d.r.Read(d.buf[:])
+		k, err := d.g.decode(d.outbuf[:], d.buf[:n])
+		if err == nil && readErr == io.EOF {
+			var m int
+			m, err = d.g.finish(d.outbuf[k:])
+			k += m
+		}
+		d.out = d.outbuf[:k]
+		if err == nil {
+			err = readErr
+		}
+		d.err = err
+	}
+	if len(d.out) > 0 {
+		n := copy(p, d.out)
+		d.out = d.out[n:]
+		return n, nil
+	}
+	return 0, d.err
+}
+
+func (d *decoder) readLegacy() {
+	input, err := io.ReadAll(d.r)
+	if err != nil {
+		d.err = err
+		return
+	}
+	d.out, d.err = d.enc.DecodeString(string(input))
+	if d.err == nil {
+		d.err = io.EOF
+	}
+}
@@ -0,0 +1,110 @@
+package base92
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestEncoder(t *testing.T) {
+	data := benchmarkData(2000)
+
+	for _, enc := range []*Encoding{StdEncoding, DefaultEncoding} {
+		for _, size := range []int{0, 1, 12, 13, 14, 100, 2000} {
+			for _, chunk := range []int{1, 5, 13, 64, 2000} {
+				var buf bytes.Buffer
+				w := NewEncoder(enc, &buf)
+				for p := data[:size]; len(p) > 0; {
+					n := min(chunk, len(p))
+					if m, err := w.Write(p[:n]); m != n || err != nil {
+						t.Fatalf("Write returned %d, %v, want %d, nil", m, err, n)
+					}
+					p = p[n:]
+				}
+				if err := w.Close(); err != nil {
+					t.Fatalf("Close failed: %v", err)
+				}
+
+				if want := enc.EncodeToString(data[:size]); buf.String() != want {
+					t.Errorf("Encoding %d bytes in chunks of %d (legacy %v) gave %q, want %q",
+						size, chunk, enc.legacy, buf.String(), want)
+				}
+			}
+		}
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	data := benchmarkData(2000)
+
+	for _, enc := range []*Encoding{StdEncoding, DefaultEncoding} {
+		for _, size := range []int{0, 1, 12, 13, 14, 100, 2000} {
+			encoded := enc.EncodeToString(data[:size])
+
+			if err := iotest.TestReader(NewDecoder(enc, strings.NewReader(encoded)), data[:size]); err != nil {
+				t.Errorf("Decoding %d bytes (legacy %v): %v", size, enc.legacy, err)
+			}
+			r := NewDecoder(enc, iotest.OneByteReader(strings.NewReader(encoded)))
+			if decoded, err := io.ReadAll(r); err != nil || !bytes.Equal(decoded, data[:size]) {
+				t.Errorf("Decoding %d bytes one at a time (legacy %v) gave %v, %v", size, enc.legacy, decoded, err)
+			}
+		}
+	}
+}
+
+func TestDecoderErrors(t *testing.T) {
+	encoded := StdEncoding.EncodeToString(benchmarkData(100))
+
+	for _, input := range []string{
+		encoded[:len(encoded)-1], // a tail no group encodes to
+		encoded[:40] + "£" + encoded[41:],
+		"~~",
+	} {
+		if _, err := io.ReadAll(NewDecoder(StdEncoding, strings.NewReader(input))); err == nil {
+			t.Errorf("Expected an error decoding %q, but got none", input)
+		}
+	}
+
+	readErr := errors.New("read failed")
+	r := io.MultiReader(strings.NewReader(encoded[:20]), iotest.ErrReader(readErr))
+	if _, err := io.ReadAll(NewDecoder(StdEncoding, r)); err != readErr {
+		t.Errorf("Expected %v, got %v", readErr, err)
+	}
+}
+
+func TestLengths(t *testing.T) {
+	data := benchmarkData(100)
+
+	for _, enc := range []*Encoding{StdEncoding, DefaultEncoding} {
+		for n := 0; n <= len(data); n++ {
+			dst := make([]byte, enc.EncodedLen(n))
+			m := enc.Encode(dst, data[:n])
+			if !enc.legacy && m != len(dst) {
+				t.Errorf("Encode wrote %d bytes, EncodedLen(%d) = %d", m, n, len(dst))
+			}
+
+			decoded := make([]byte, enc.DecodedLen(m))
+			k, err := enc.Decode(decoded, dst[:m])
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			if !bytes.Equal(decoded[:k], data[:n]) {
+				t.Errorf("Round trip of %d bytes (legacy %v) gave %v", n, enc.legacy, decoded[:k])
+			}
+			if !enc.legacy && k != len(decoded) {
+				t.Errorf("Decode wrote %d bytes, DecodedLen(%d) = %d", k, m, len(decoded))
+			}
+		}
+	}
+
+	// The legacy bound holds for the longest input of each length.
+	for n := 1; n <= 100; n++ {
+		max := bytes.Repeat([]byte{0xFF}, n)
+		if m := len(DefaultEncoding.EncodeToString(max)); m > DefaultEncoding.EncodedLen(n) {
+			t.Errorf("Legacy encoding of %d bytes has %d symbols, EncodedLen = %d", n, m, DefaultEncoding.EncodedLen(n))
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package codec
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+)
+
+// base58Alphabet is the Bitcoin alphabet, which leaves out 0, O, I and l.
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var base58DecodeMap = decodeMap(base58Alphabet)
+
+// decodeMap returns the digit of each byte of alphabet, and 0xFF for the
+// other bytes.
+func decodeMap(alphabet string) [256]byte {
+	var m [256]byte
+	for i := range m {
+		m[i] = 0xFF
+	}
+	for i := 0; i < len(alphabet); i++ {
+		m[alphabet[i]] = byte(i)
+	}
+	return m
+}
+
+// newBase58Encoder returns an encoder that writes the whole input as one
+// number, with each leading zero byte written as a leading 1. This takes
+// time quadratic in the input, so base58 is meant for short values such as
+// keys, and its encoder and decoder buffer all of their input.
+func newBase58Encoder(w io.Writer) io.WriteCloser {
+	return &wholeEncoder{w: w, encode: func(src []byte) ([]byte, error) { return base58Encode(src), nil }}
+}
+
+func newBase58Decoder(r io.Reader) io.Reader {
+	return &wholeDecoder{r: r, decode: base58Decode}
+}
+
+func base58Encode(src []byte) []byte {
+	zeros := 0
+	for zeros < len(src) && src[zeros] == 0 {
+		zeros++
+	}
+
+	x := new(big.Int).SetBytes(src)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+	var encoded []byte
+	for x.Sign() > 0 {
+		x.DivMod(x, radix, mod)
+		encoded = append(encoded, base58Alphabet[mod.Int64()])
+	}
+	for i := 0; i < zeros; i++ {
+		encoded = append(encoded, base58Alphabet[0])
+	}
+
+	for i, j := 0, len(encoded)-1; i < j; i, j = i+1, j-1 {
+		encoded[i], encoded[j] = encoded[j], encoded[i]
+	}
+	return encoded
+}
+
+func base58Decode(src []byte) ([]byte, error) {
+	zeros := 0
+	for zeros < len(src) && src[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+
+	x := new(big.Int)
+	radix := big.NewInt(58)
+	digit := new(big.Int)
+	for i := zeros; i < len(src); i++ {
+		d := base58DecodeMap[src[i]]
+		if d == 0xFF {
+			return nil, corruptAt("base58", int64(i))
+		}
+		x.Mul(x, radix)
+		x.Add(x, digit.SetInt64(int64(d)))
+	}
+	return append(make([]byte, zeros), x.Bytes()...), nil
+}
+
+// wholeEncoder buffers its input and encodes it on Close, for encodings
+// that cannot be computed a group at a time.
+type wholeEncoder struct {
+	w      io.Writer
+	buf    bytes.Buffer
+	encode func([]byte) ([]byte, error)
+}
+
+func (e *wholeEncoder) Write(p []byte) (int, error) {
+	return e.buf.Write(p)
+}
+
+func (e *wholeEncoder) Close() error {
+	encoded, err := e.encode(e.buf.Bytes())
+	e.buf.Reset()
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(encoded)
+	return err
+}
+
+// wholeDecoder reads all of its input before decoding it.
+type wholeDecoder struct {
+	r      io.Reader
+	decode func([]byte) ([]byte, error)
+	out    []byte
+	err    error
+}
+
+func (d *wholeDecoder) Read(p []byte) (int, error) {
+	if d.r != nil {
+		input, err := io.ReadAll(d.r)
+		d.r = nil
+		if err == nil {
+			d.out, err = d.decode(input)
+		}
+		d.err = err
+		if d.err == nil {
+			d.err = io.EOF
+		}
+	}
+	if len(d.out) > 0 {
+		n := copy(p, d.out)
+		d.out = d.out[n:]
+		return n, nil
+	}
+	return 0, d.err
+}
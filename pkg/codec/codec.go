@@ -0,0 +1,241 @@
+// Package codec provides text encodings of binary data behind a common
+// interface, so that a program can offer them by name.
+//
+// The registered codecs are hex, base32 (RFC 4648, padded), base58 (the
+// Bitcoin alphabet), z85 (ZeroMQ), base64 and url64 (RFC 4648, unpadded),
+// base92 and base92-legacy (see package base92), paper (see package paper)
+// and words (see package words). Register adds more.
+// The base64 decoders accept input with or without padding.
+package codec
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/presbrey/argon2aes/pkg/base92"
+	"github.com/presbrey/argon2aes/pkg/paper"
+	"github.com/presbrey/argon2aes/pkg/words"
+)
+
+// Codec is a text encoding of binary data.
+type Codec interface {
+	// Name returns the name the codec is registered under.
+	Name() string
+
+	// NewEncoder returns a writer that writes the encoding of the bytes
+	// written to it to w. Close flushes any partial output; it does not
+	// close w.
+	NewEncoder(w io.Writer) io.WriteCloser
+
+	// NewDecoder returns a reader that decodes the encoding read from r.
+	NewDecoder(r io.Reader) io.Reader
+}
+
+// ErrCorrupt matches, with errors.Is, the errors returned by the decoders
+// of codecs made with New for input that is not a valid encoding. Errors
+// from the underlying reader are returned unchanged.
+var ErrCorrupt = errors.New("corrupt input")
+
+// New returns a Codec that makes its encoders and decoders with the given
+// functions.
+func New(name string, newEncoder func(io.Writer) io.WriteCloser, newDecoder func(io.Reader) io.Reader) Codec {
+	return &funcCodec{name, newEncoder, newDecoder}
+}
+
+type funcCodec struct {
+	name       string
+	newEncoder func(io.Writer) io.WriteCloser
+	newDecoder func(io.Reader) io.Reader
+}
+
+func (c *funcCodec) Name() string { return c.name }
+
+func (c *funcCodec) NewEncoder(w io.Writer) io.WriteCloser { return c.newEncoder(w) }
+
+func (c *funcCodec) NewDecoder(r io.Reader) io.Reader {
+	src := &sourceReader{r: r}
+	return &decoder{r: c.newDecoder(src), src: src}
+}
+
+// sourceReader remembers the last error of the reader a decoder reads from.
+type sourceReader struct {
+	r   io.Reader
+	err error
+}
+
+func (s *sourceReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	if err != nil && err != io.EOF {
+		s.err = err
+	}
+	return n, err
+}
+
+// decoder marks the errors of a decoder that did not come from its source
+// as ErrCorrupt.
+type decoder struct {
+	r   io.Reader
+	src *sourceReader
+}
+
+func (d *decoder) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	if err != nil && err != io.EOF && (d.src.err == nil || !errors.Is(err, d.src.err)) {
+		err = &corruptError{err}
+	}
+	return n, err
+}
+
+type corruptError struct {
+	err error
+}
+
+func (e *corruptError) Error() string { return e.err.Error() }
+
+func (e *corruptError) Unwrap() []error { return []error{ErrCorrupt, e.err} }
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Codec)
+)
+
+// Register makes c available by name. It panics if c has no name or a codec
+// of the same name is already registered.
+func Register(c Codec) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	name := c.Name()
+	if name == "" {
+		panic("codec: Register of a codec without a name")
+	}
+	if _, ok := registry[name]; ok {
+		panic("codec: Register called twice for " + name)
+	}
+	registry[name] = c
+}
+
+// Lookup returns the codec registered under name, or nil if there is none.
+func Lookup(name string) Codec {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return registry[name]
+}
+
+// Names returns the names of the registered codecs in sorted order.
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// EncodeToString returns the encoding of src with c.
+func EncodeToString(c Codec, src []byte) string {
+	var b strings.Builder
+	w := c.NewEncoder(&b)
+	w.Write(src) // a strings.Builder does not fail
+	w.Close()
+	return b.String()
+}
+
+// DecodeString returns the bytes s encodes with c.
+func DecodeString(c Codec, s string) ([]byte, error) {
+	return io.ReadAll(c.NewDecoder(strings.NewReader(s)))
+}
+
+// Base92 returns a codec for enc under the given name, for an alphabet
+// other than those of the registered base92 codecs. Its decoder ignores
+// whitespace.
+func Base92(name string, enc *base92.Encoding) Codec {
+	dec := enc.IgnoreWhitespace()
+	return New(name,
+		func(w io.Writer) io.WriteCloser { return base92.NewEncoder(enc, w) },
+		func(r io.Reader) io.Reader { return base92.NewDecoder(dec, r) })
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// base64Input prepares base64 for a decoder of unpadded base64 by dropping
+// the '=' padding. If anyAlphabet is set, it also maps the URL-safe
+// alphabet onto the standard one.
+type base64Input struct {
+	r           io.Reader
+	anyAlphabet bool
+}
+
+func (b *base64Input) Read(p []byte) (int, error) {
+	for {
+		n, err := b.r.Read(p)
+		m := 0
+		for _, c := range p[:n] {
+			switch {
+			case c == '=':
+				continue
+			case c == '-' && b.anyAlphabet:
+				c = '+'
+			case c == '_' && b.anyAlphabet:
+				c = '/'
+			}
+			p[m] = c
+			m++
+		}
+		if m > 0 || n == 0 || err != nil {
+			return m, err
+		}
+	}
+}
+
+func init() {
+	Register(New("hex",
+		func(w io.Writer) io.WriteCloser { return nopCloser{hex.NewEncoder(w)} },
+		hex.NewDecoder))
+	Register(New("base32",
+		func(w io.Writer) io.WriteCloser { return base32.NewEncoder(base32.StdEncoding, w) },
+		func(r io.Reader) io.Reader { return base32.NewDecoder(base32.StdEncoding, r) }))
+	Register(New("base58", newBase58Encoder, newBase58Decoder))
+	Register(New("z85", newZ85Encoder, newZ85Decoder))
+	Register(New("base64",
+		func(w io.Writer) io.WriteCloser { return base64.NewEncoder(base64.RawStdEncoding, w) },
+		func(r io.Reader) io.Reader { return base64.NewDecoder(base64.RawStdEncoding, &base64Input{r: r}) }))
+	Register(New("url64",
+		func(w io.Writer) io.WriteCloser { return base64.NewEncoder(base64.RawURLEncoding, w) },
+		func(r io.Reader) io.Reader { return base64.NewDecoder(base64.RawURLEncoding, &base64Input{r: r}) }))
+	Register(Base92("base92", base92.StdEncoding))
+	Register(Base92("base92-legacy", base92.DefaultEncoding))
+	Register(New("words", words.NewEncoder, words.NewDecoder))
+	Register(New("paper",
+		func(w io.Writer) io.WriteCloser { return &wholeEncoder{w: w, encode: paperEncode} },
+		func(r io.Reader) io.Reader { return &wholeDecoder{r: r, decode: paperDecode} }))
+}
+
+// paperEncode and paperDecode adapt package paper to wholeEncoder and
+// wholeDecoder. The decoder corrects what it can without reporting it.
+func paperEncode(src []byte) ([]byte, error) {
+	text, err := paper.Encode(src)
+	return []byte(text), err
+}
+
+func paperDecode(src []byte) ([]byte, error) {
+	data, _, err := paper.Decode(string(src))
+	return data, err
+}
+
+// corruptAt returns the error for an invalid byte of the named encoding.
+func corruptAt(name string, offset int64) error {
+	return fmt.Errorf("illegal %s data at input byte %d", name, offset)
+}
@@ -0,0 +1,192 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestVectors(t *testing.T) {
+	testCases := []struct {
+		codec   string
+		decoded []byte
+		encoded string
+	}{
+		{"hex", []byte("Hi!"), "486921"},
+		{"base32", []byte("foobar"), "MZXW6YTBOI======"},
+		{"base58", []byte("Hello World!"), "2NEpo7TZRRrLZSi2U"},
+		{"base58", []byte{0, 0, 0x28, 0x7f, 0xb4, 0xcd}, "11233QC4"},
+		{"base58", []byte{0}, "1"},
+		{"z85", []byte{0x86, 0x4F, 0xD2, 0x6F, 0xB5, 0x59, 0xF7, 0x5B}, "HelloWorld"},
+		{"z85", []byte{0x86, 0x4F, 0xD2}, "Helj"},
+		{"base64", []byte("Hi!?"), "SGkhPw"},
+		{"url64", []byte{0xfb, 0xff}, "-_8"},
+		{"base92", []byte{0}, "00"},
+		{"words", []byte("Hi!"), "embody erupt capable"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.codec+"/"+tc.encoded, func(t *testing.T) {
+			c := Lookup(tc.codec)
+			if c == nil {
+				t.Fatalf("Codec %s is not registered", tc.codec)
+			}
+			if got := EncodeToString(c, tc.decoded); got != tc.encoded {
+				t.Errorf("EncodeToString(%v) = %q, want %q", tc.decoded, got, tc.encoded)
+			}
+			got, err := DecodeString(c, tc.encoded)
+			if err != nil || !bytes.Equal(got, tc.decoded) {
+				t.Errorf("DecodeString(%q) = %v, %v, want %v", tc.encoded, got, err, tc.decoded)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	data := make([]byte, 3000)
+	for i := range data {
+		data[i] = byte(i*131 + 7)
+	}
+
+	for _, name := range Names() {
+		c := Lookup(name)
+		for _, size := range []int{0, 1, 2, 3, 4, 5, 13, 100, 3000} {
+			if name == "base58" && size > 100 {
+				continue // quadratic
+			}
+			var buf bytes.Buffer
+			w := c.NewEncoder(&buf)
+			for p := data[:size]; len(p) > 0; {
+				n := min(7, len(p))
+				if _, err := w.Write(p[:n]); err != nil {
+					t.Fatalf("%s: Write failed: %v", name, err)
+				}
+				p = p[n:]
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("%s: Close failed: %v", name, err)
+			}
+
+			r := c.NewDecoder(iotest.OneByteReader(&buf))
+			if decoded, err := io.ReadAll(r); err != nil || !bytes.Equal(decoded, data[:size]) {
+				t.Errorf("%s: round trip of %d bytes gave %v, %v", name, size, decoded, err)
+			}
+		}
+	}
+}
+
+func TestCorrupt(t *testing.T) {
+	testCases := []struct {
+		codec string
+		input string
+	}{
+		{"hex", "4g"},
+		{"hex", "486"},
+		{"base32", "MZXW6YT!"},
+		{"base58", "0OIl"},
+		{"z85", "Hello\""},
+		{"z85", "H"},
+		{"z85", "#####"}, // 85^5-1 does not fit in 4 bytes
+		{"base64", "SGk*"},
+		{"url64", "+/8"},
+		{"base92", "~~"},
+		{"paper", "00000 00000"},
+		{"words", "embody erupt cable"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.codec+"/"+tc.input, func(t *testing.T) {
+			if _, err := DecodeString(Lookup(tc.codec), tc.input); !errors.Is(err, ErrCorrupt) {
+				t.Errorf("Expected ErrCorrupt decoding %q, got %v", tc.input, err)
+			}
+		})
+	}
+
+	readErr := errors.New("read failed")
+	for _, name := range Names() {
+		r := io.MultiReader(strings.NewReader("00"), iotest.ErrReader(readErr))
+		if _, err := io.ReadAll(Lookup(name).NewDecoder(r)); err != readErr {
+			t.Errorf("%s: expected %v, got %v", name, readErr, err)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	for _, name := range []string{"hex", "base32", "base58", "z85", "base64", "url64", "base92", "base92-legacy", "paper", "words"} {
+		if c := Lookup(name); c == nil || c.Name() != name {
+			t.Errorf("Lookup(%q) = %v", name, c)
+		}
+	}
+	if Lookup("rot13") != nil {
+		t.Error("Expected no codec named rot13")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected Register to panic for a duplicate name, but it did not")
+		}
+	}()
+	Register(Lookup("hex"))
+}
+
+func TestDetect(t *testing.T) {
+	prefix := []byte("\x89A2A\r\n\x1a\n")
+	data := append(append([]byte{}, prefix...), make([]byte, 500)...)
+	for i := len(prefix); i < len(data); i++ {
+		data[i] = byte(i*131 + 7)
+	}
+	short := prefix[:len(prefix):len(prefix)]
+
+	testCases := []struct {
+		name    string
+		data    []byte
+		encoded string
+		codec   string // "" for none
+	}{
+		{"Raw", data, string(data), ""},
+		{"Base64", data, base64.RawStdEncoding.EncodeToString(data), "base64"},
+		{"Base64Padded", data, base64.StdEncoding.EncodeToString(data), "base64"},
+		{"URL64", data, base64.RawURLEncoding.EncodeToString(data), "url64"},
+		{"URL64Padded", data, base64.URLEncoding.EncodeToString(data), "url64"},
+		{"Base92", data, EncodeToString(Lookup("base92"), data) + "\n", "base92"},
+		{"Base32", data, EncodeToString(Lookup("base32"), data), "base32"},
+		{"Hex", data, EncodeToString(Lookup("hex"), data), "hex"},
+		{"Z85", data, EncodeToString(Lookup("z85"), data), "z85"},
+		{"Words", data, EncodeToString(Lookup("words"), data), "words"},
+		{"Paper", data, EncodeToString(Lookup("paper"), data), "paper"},
+		{"Short", short, EncodeToString(Lookup("base92"), short), "base92"},
+		{"Base58", data, EncodeToString(Lookup("base58"), data), ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, r, err := Detect(strings.NewReader(tc.encoded), prefix)
+			if err != nil {
+				t.Fatalf("Detect failed: %v", err)
+			}
+			name := ""
+			if c != nil {
+				name = c.Name()
+				r = c.NewDecoder(r)
+			}
+			if name != tc.codec {
+				t.Fatalf("Detected %q, want %q", name, tc.codec)
+			}
+			decoded, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll failed: %v", err)
+			}
+			want := tc.data
+			if c == nil {
+				want = []byte(tc.encoded) // passed through unchanged
+			}
+			if !bytes.Equal(decoded, want) {
+				t.Errorf("Read %d bytes, want %d", len(decoded), len(want))
+			}
+		})
+	}
+}
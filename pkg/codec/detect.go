@@ -0,0 +1,66 @@
+package codec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"io"
+
+	"github.com/presbrey/argon2aes/pkg/paper"
+)
+
+// detectLength is how much of the input Detect decodes: a whole number of
+// groups of each codec it tries.
+const detectLength = 160
+
+// anyBase64 and anyURL64 decode base64 in either alphabet, with or without
+// padding, and encode their own alphabet without padding.
+var (
+	anyBase64 = newAnyBase64("base64", base64.RawStdEncoding)
+	anyURL64  = newAnyBase64("url64", base64.RawURLEncoding)
+)
+
+func newAnyBase64(name string, enc *base64.Encoding) Codec {
+	return New(name,
+		func(w io.Writer) io.WriteCloser { return base64.NewEncoder(enc, w) },
+		func(r io.Reader) io.Reader {
+			return base64.NewDecoder(base64.RawStdEncoding, &base64Input{r: r, anyAlphabet: true})
+		})
+}
+
+// Detect identifies the encoding of data known to start with prefix, such
+// as the signature of a file format, by decoding the start of r. It
+// returns nil if r starts with prefix itself or no encoding matches, along
+// with a reader of all of r.
+//
+// Detect recognizes base64 in either alphabet, with or without padding,
+// base92, base32, hex, z85 and words, and paper by the heading of its
+// comment line. For base64 it returns a codec that decodes both alphabets, named
+// url64 if the start of r contains - or _. Base58 and base92-legacy encode
+// the input as a single number, so they cannot be recognized from its
+// start.
+func Detect(r io.Reader, prefix []byte) (Codec, io.Reader, error) {
+	br := bufio.NewReaderSize(r, detectLength)
+	start, err := br.Peek(detectLength)
+	if err != nil && err != io.EOF {
+		return nil, nil, err
+	}
+	if bytes.HasPrefix(start, prefix) {
+		return nil, br, nil
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(start), []byte(paper.Heading)) {
+		return Lookup("paper"), br, nil
+	}
+
+	for _, c := range []Codec{anyBase64, Lookup("base92"), Lookup("base32"), Lookup("hex"), Lookup("z85"), Lookup("words")} {
+		decoded := make([]byte, len(prefix))
+		_, err := io.ReadFull(c.NewDecoder(bytes.NewReader(start)), decoded)
+		if err == nil && bytes.Equal(decoded, prefix) {
+			if c == anyBase64 && bytes.ContainsAny(start, "-_") {
+				c = anyURL64
+			}
+			return c, br, nil
+		}
+	}
+	return nil, br, nil
+}
@@ -0,0 +1,186 @@
+package codec
+
+import (
+	"io"
+	"math"
+)
+
+// Z85 (ZeroMQ RFC 32) writes each 4 bytes, read as a big-endian number, as
+// 5 base85 digits. The RFC only encodes multiples of 4 bytes; as in
+// Ascii85, a final group of k < 4 bytes is padded with zeros and written as
+// its first k+1 digits, and decoded by padding it with the highest digit.
+const z85Alphabet = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ.-:+=^!/*?&<>()[]{}@%$#"
+
+var z85DecodeMap = decodeMap(z85Alphabet)
+
+// z85EncodeGroup writes the len(src)+1 leading digits of the group src.
+func z85EncodeGroup(dst, src []byte) {
+	var v uint32
+	for i := 0; i < 4; i++ {
+		v <<= 8
+		if i < len(src) {
+			v |= uint32(src[i])
+		}
+	}
+	var digits [5]byte
+	for i := 4; i >= 0; i-- {
+		digits[i] = z85Alphabet[v%85]
+		v /= 85
+	}
+	copy(dst, digits[:len(src)+1])
+}
+
+// z85DecodeGroup reads a group of 2 to 5 digits into len(digits)-1 bytes
+// of dst. It reports false if their value does not fit in 4 bytes.
+func z85DecodeGroup(dst, digits []byte) bool {
+	var v uint64
+	for i := 0; i < 5; i++ {
+		d := uint64(84)
+		if i < len(digits) {
+			d = uint64(digits[i])
+		}
+		v = v*85 + d
+	}
+	if v > math.MaxUint32 {
+		return false
+	}
+	for i := 0; i < len(digits)-1; i++ {
+		dst[i] = byte(v >> (24 - 8*i))
+	}
+	return true
+}
+
+func newZ85Encoder(w io.Writer) io.WriteCloser {
+	return &z85Encoder{w: w}
+}
+
+type z85Encoder struct {
+	w    io.Writer
+	err  error
+	buf  [4]byte // partial group
+	nbuf int
+	out  [256 * 5]byte
+	nout int
+}
+
+func (e *z85Encoder) Write(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	n := 0
+	for len(p) > 0 {
+		k := copy(e.buf[e.nbuf:], p)
+		e.nbuf += k
+		n, p = n+k, p[k:]
+		if e.nbuf < len(e.buf) {
+			break
+		}
+		z85EncodeGroup(e.out[e.nout:], e.buf[:])
+		e.nbuf = 0
+		e.nout += 5
+		if e.nout == len(e.out) && e.flush() != nil {
+			return n, e.err
+		}
+	}
+	return n, e.flush()
+}
+
+func (e *z85Encoder) flush() error {
+	if e.nout > 0 && e.err == nil {
+		_, e.err = e.w.Write(e.out[:e.nout])
+		e.nout = 0
+	}
+	return e.err
+}
+
+// Close writes the final partial group, if any. It does not close the
+// underlying writer.
+func (e *z85Encoder) Close() error {
+	if e.err != nil {
+		return e.err
+	}
+	if e.nbuf > 0 {
+		z85EncodeGroup(e.out[e.nout:], e.buf[:e.nbuf])
+		e.nout += e.nbuf + 1
+		e.nbuf = 0
+	}
+	return e.flush()
+}
+
+func newZ85Decoder(r io.Reader) io.Reader {
+	return &z85Decoder{r: r}
+}
+
+type z85Decoder struct {
+	r      io.Reader
+	err    error // returned once out is empty
+	digits [5]byte
+	n      int   // number of digits held
+	start  int64 // offset of the first digit held
+	offset int64 // offset of the next input byte
+	buf    [1024]byte
+	outbuf [1024]byte
+	out    []byte // decoded but not yet returned
+}
+
+func (d *z85Decoder) Read(p []byte) (int, error) {
+	for len(d.out) == 0 && d.err == nil {
+		n, readErr := d.r.Read(d.buf[:])
+		k, err := d.decode(d.outbuf[:], d.buf[:n])
+		if err == nil && readErr == io.EOF {
+			var m int
+			m, err = d.finish(d.outbuf[k:])
+			k += m
+		}
+		d.out = d.outbuf[:k]
+		if err == nil {
+			err = readErr
+		}
+		d.err = err
+	}
+	if len(d.out) > 0 {
+		n := copy(p, d.out)
+		d.out = d.out[n:]
+		return n, nil
+	}
+	return 0, d.err
+}
+
+// decode decodes the complete groups of src, after any digits held, and
+// holds back a final partial group.
+func (d *z85Decoder) decode(dst, src []byte) (int, error) {
+	j := 0
+	for i, c := range src {
+		v := z85DecodeMap[c]
+		if v == 0xFF {
+			return j, corruptAt("z85", d.offset+int64(i))
+		}
+		if d.n == 0 {
+			d.start = d.offset + int64(i)
+		}
+		d.digits[d.n] = v
+		d.n++
+		if d.n == len(d.digits) {
+			if !z85DecodeGroup(dst[j:], d.digits[:]) {
+				return j, corruptAt("z85", d.start)
+			}
+			j += 4
+			d.n = 0
+		}
+	}
+	d.offset += int64(len(src))
+	return j, nil
+}
+
+// finish decodes the final partial group, if any.
+func (d *z85Decoder) finish(dst []byte) (int, error) {
+	if d.n == 0 {
+		return 0, nil
+	}
+	if d.n == 1 || !z85DecodeGroup(dst, d.digits[:d.n]) {
+		return 0, corruptAt("z85", d.start)
+	}
+	k := d.n - 1
+	d.n = 0
+	return k, nil
+}
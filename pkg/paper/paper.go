@@ -0,0 +1,205 @@
+// Package paper encodes data for printing on paper and typing back in,
+// such as the backup of a key.
+//
+// The data is written in Crockford's base32, which has no I, L, O or U and
+// is read without regard to case, in lines of 30 characters. Each line is a
+// Reed-Solomon codeword of 2 characters of line number, 22 of data and 6 of
+// parity, so up to 3 mistyped characters per line are found and corrected.
+// The data is preceded by its length and followed by its CRC-32, which
+// catch the rare miscorrection of a line with more mistakes.
+package paper
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"strings"
+)
+
+const (
+	// Alphabet is Crockford's base32 alphabet.
+	Alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+	// Heading starts the comment line that Encode writes.
+	Heading = "# a2a paper backup"
+)
+
+const (
+	indexSymbols  = 2
+	dataSymbols   = 22
+	paritySymbols = 6
+	lineSymbols   = indexSymbols + dataSymbols + paritySymbols
+
+	// MaxLines is the number of line numbers of 2 characters.
+	MaxLines = fieldSize * fieldSize
+
+	// MaxSize is the most data that fits in MaxLines.
+	MaxSize = MaxLines*dataSymbols*5/8 - 8
+
+	// groupSize is the number of characters printed together.
+	groupSize = 5
+)
+
+var decodeMap = func() [256]byte {
+	var m [256]byte
+	for i := range m {
+		m[i] = 0xFF
+	}
+	lower := strings.ToLower(Alphabet)
+	for i := 0; i < len(Alphabet); i++ {
+		m[Alphabet[i]] = byte(i)
+		m[lower[i]] = byte(i)
+	}
+	// Crockford's base32 reads the letters it leaves out as the digits
+	// they resemble.
+	for _, c := range "Oo" {
+		m[c] = 0
+	}
+	for _, c := range "IiLl" {
+		m[c] = 1
+	}
+	return m
+}()
+
+// Encode returns data laid out for printing, with a comment line that
+// describes it. It fails if data is longer than MaxSize.
+func Encode(data []byte) (string, error) {
+	if len(data) > MaxSize {
+		return "", fmt.Errorf("paper backup holds at most %d bytes, got %d", MaxSize, len(data))
+	}
+	payload := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	payload = append(payload, data...)
+	payload = binary.BigEndian.AppendUint32(payload, crc32.ChecksumIEEE(data))
+	symbols := toSymbols(payload)
+
+	lines := (len(symbols) + dataSymbols - 1) / dataSymbols
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d bytes in %d lines, each correcting up to %d mistyped characters\n",
+		Heading, len(data), lines, paritySymbols/2)
+	for n := 0; n < lines; n++ {
+		line := make([]byte, 0, lineSymbols)
+		line = append(line, byte(n/fieldSize), byte(n%fieldSize))
+		chunk := symbols[n*dataSymbols : min((n+1)*dataSymbols, len(symbols))]
+		line = append(line, chunk...)
+		line = append(line, make([]byte, dataSymbols-len(chunk))...)
+		line = append(line, rsEncode(line)...)
+
+		for i, s := range line {
+			if i > 0 && i%groupSize == 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteByte(Alphabet[s])
+		}
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
+
+// Correction is the position of a character that Decode corrected.
+type Correction struct {
+	Line   int // line of the text, from 1
+	Column int // character of the line, from 1, not counting spaces
+}
+
+// Decode returns the data of a backup made by Encode, and the characters
+// it corrected. Lines may be in any order, spaces and hyphens are ignored,
+// and so are empty lines and lines starting with #.
+func Decode(text string) ([]byte, []Correction, error) {
+	var corrections []Correction
+	var data [][]byte // the data symbols of each line, by line number
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		codeword := make([]byte, 0, lineSymbols)
+		for j := 0; j < len(line); j++ {
+			switch c := line[j]; {
+			case c == ' ' || c == '-' || c == '\t':
+			case decodeMap[c] != 0xFF:
+				codeword = append(codeword, decodeMap[c])
+			default:
+				// Leave an unknown character for the parity to correct.
+				codeword = append(codeword, 0)
+			}
+		}
+		if len(codeword) != lineSymbols {
+			return nil, corrections, fmt.Errorf("line %d has %d characters, want %d", i+1, len(codeword), lineSymbols)
+		}
+		fixed, ok := rsCorrect(codeword)
+		if !ok {
+			return nil, corrections, fmt.Errorf("line %d has too many mistakes to correct", i+1)
+		}
+		for _, j := range fixed {
+			corrections = append(corrections, Correction{i + 1, j + 1})
+		}
+
+		n := int(codeword[0])*fieldSize + int(codeword[1])
+		for len(data) <= n {
+			data = append(data, nil)
+		}
+		if data[n] != nil {
+			return nil, corrections, fmt.Errorf("line %d repeats backup line %d", i+1, n+1)
+		}
+		data[n] = codeword[indexSymbols : indexSymbols+dataSymbols]
+	}
+
+	var symbols []byte
+	for n, d := range data {
+		if d == nil {
+			return nil, corrections, fmt.Errorf("backup line %d is missing", n+1)
+		}
+		symbols = append(symbols, d...)
+	}
+	payload := fromSymbols(symbols)
+	if len(payload) < 8 {
+		return nil, corrections, fmt.Errorf("no paper backup found")
+	}
+	size := binary.BigEndian.Uint32(payload)
+	if uint64(size)+8 > uint64(len(payload)) {
+		return nil, corrections, fmt.Errorf("backup of %d bytes is missing lines at the end", size)
+	}
+	result := payload[4 : 4+size]
+	if crc32.ChecksumIEEE(result) != binary.BigEndian.Uint32(payload[4+size:]) {
+		return nil, corrections, fmt.Errorf("checksum mismatch: too many mistakes to correct")
+	}
+	return result, corrections, nil
+}
+
+// toSymbols splits b into 5-bit symbols, most significant bits first,
+// padding the last one with zeros.
+func toSymbols(b []byte) []byte {
+	symbols := make([]byte, 0, (len(b)*8+4)/5)
+	var acc uint
+	bits := 0
+	for _, c := range b {
+		acc = acc<<8 | uint(c)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			symbols = append(symbols, byte(acc>>bits)&0x1F)
+		}
+	}
+	if bits > 0 {
+		symbols = append(symbols, byte(acc<<(5-bits))&0x1F)
+	}
+	return symbols
+}
+
+// fromSymbols joins 5-bit symbols into bytes, dropping any bits short of
+// a byte.
+func fromSymbols(symbols []byte) []byte {
+	b := make([]byte, 0, len(symbols)*5/8)
+	var acc uint
+	bits := 0
+	for _, s := range symbols {
+		acc = acc<<5 | uint(s)
+		bits += 5
+		if bits >= 8 {
+			bits -= 8
+			b = append(b, byte(acc>>bits))
+		}
+	}
+	return b
+}
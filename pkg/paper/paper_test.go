@@ -0,0 +1,182 @@
+package paper
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func testData(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i*131 + 7)
+	}
+	return data
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, size := range []int{0, 1, 5, 13, 32, 100, 1000} {
+		data := testData(size)
+		text, err := Encode(data)
+		if err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n")[1:] {
+			if len(line) != lineSymbols+lineSymbols/groupSize-1 {
+				t.Errorf("Line %q has %d columns", line, len(line))
+			}
+		}
+
+		decoded, corrections, err := Decode(text)
+		if err != nil || !bytes.Equal(decoded, data) || len(corrections) != 0 {
+			t.Errorf("Decoding %d bytes gave %v, %v, %v", size, decoded, corrections, err)
+		}
+	}
+
+	if _, err := Encode(make([]byte, MaxSize+1)); err == nil {
+		t.Error("Expected an error for data over MaxSize, but got none")
+	}
+	data := testData(MaxSize)
+	text, err := Encode(data)
+	if err != nil {
+		t.Fatalf("Encode of MaxSize bytes failed: %v", err)
+	}
+	if decoded, _, err := Decode(text); err != nil || !bytes.Equal(decoded, data) {
+		t.Errorf("Round trip of MaxSize bytes failed: %v", err)
+	}
+}
+
+// typo replaces the character at column col (from 0, not counting spaces)
+// of line with another.
+func typo(line string, col int, rng *rand.Rand) string {
+	b := []byte(line)
+	for i := range b {
+		if b[i] == ' ' {
+			continue
+		}
+		if col == 0 {
+			old := b[i]
+			for b[i] == old {
+				b[i] = Alphabet[rng.Intn(len(Alphabet))]
+			}
+			break
+		}
+		col--
+	}
+	return string(b)
+}
+
+func TestCorrection(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	data := testData(64)
+	text, err := Encode(data)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	lines := strings.Split(text, "\n")
+
+	for trial := 0; trial < 200; trial++ {
+		mistakes := 1 + trial%(paritySymbols/2)
+		damaged := append([]string(nil), lines...)
+		want := make(map[Correction]bool)
+		for n := 1; n < len(damaged)-1; n++ {
+			for _, col := range rng.Perm(lineSymbols)[:mistakes] {
+				damaged[n] = typo(damaged[n], col, rng)
+				want[Correction{n + 1, col + 1}] = true
+			}
+		}
+
+		decoded, corrections, err := Decode(strings.Join(damaged, "\n"))
+		if err != nil || !bytes.Equal(decoded, data) {
+			t.Fatalf("Decoding with %d mistakes per line failed: %v", mistakes, err)
+		}
+		if len(corrections) != len(want) {
+			t.Errorf("Got %d corrections, want %d", len(corrections), len(want))
+		}
+		for _, c := range corrections {
+			if !want[c] {
+				t.Errorf("Unexpected correction %+v", c)
+			}
+		}
+	}
+
+	// More mistakes than the parity corrects are never accepted.
+	for trial := 0; trial < 200; trial++ {
+		damaged := append([]string(nil), lines...)
+		for _, col := range rng.Perm(lineSymbols)[:paritySymbols/2+1+trial%3] {
+			damaged[1] = typo(damaged[1], col, rng)
+		}
+		if decoded, _, err := Decode(strings.Join(damaged, "\n")); err == nil && !bytes.Equal(decoded, data) {
+			t.Fatalf("Decoding too many mistakes returned wrong data without an error")
+		}
+	}
+}
+
+func TestDecodeLeniency(t *testing.T) {
+	data := testData(40)
+	text, err := Encode(data)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	swapped := append([]string{lines[0], lines[2], lines[1]}, lines[3:]...)
+
+	testCases := []struct {
+		name string
+		text string
+	}{
+		{"Lowercase", strings.ToLower(text)},
+		{"Lookalikes", strings.NewReplacer("0", "O", "1", "l").Replace(text)},
+		{"Hyphens", strings.ReplaceAll(text, " ", "-")},
+		{"NoSpaces", strings.ReplaceAll(text, " ", "")},
+		{"CRLF", strings.ReplaceAll(text, "\n", "\r\n")},
+		{"Reordered", strings.Join(swapped, "\n")},
+		{"UnknownCharacter", strings.Replace(text, lines[1][:1], "U", 1)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			decoded, _, err := Decode(tc.text)
+			if err != nil || !bytes.Equal(decoded, data) {
+				t.Errorf("Decode gave %v, %v", decoded, err)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	text, err := Encode(testData(100))
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+
+	testCases := []struct {
+		name string
+		text string
+	}{
+		{"Empty", ""},
+		{"ShortLine", strings.Replace(text, lines[1], lines[1][:len(lines[1])-1], 1)},
+		{"MissingLine", strings.Replace(text, lines[2]+"\n", "", 1)},
+		{"MissingLastLine", strings.Join(lines[:len(lines)-1], "\n")},
+		{"RepeatedLine", text + lines[1] + "\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, _, err := Decode(tc.text); err == nil {
+				t.Error("Expected an error, but got none")
+			}
+		})
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	for size := 0; size < 20; size++ {
+		data := testData(size)
+		if got := fromSymbols(toSymbols(data)); !bytes.Equal(got, data) {
+			t.Errorf("Symbols of %v gave back %v", data, got)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package paper
+
+// Reed-Solomon coding over GF(32), whose elements are the 5-bit values of
+// one base32 character, so that each mistyped character is one symbol
+// error. Field elements are polynomials over GF(2) modulo the primitive
+// polynomial x^5 + x^2 + 1; α = x generates the multiplicative group.
+const (
+	fieldSize = 32
+	fieldPoly = 0x25
+)
+
+var gfExp, gfLog = func() (exp [2 * fieldSize]byte, log [fieldSize]byte) {
+	x := 1
+	for i := 0; i < fieldSize-1; i++ {
+		exp[i], exp[i+fieldSize-1] = byte(x), byte(x)
+		log[x] = byte(i)
+		x <<= 1
+		if x&fieldSize != 0 {
+			x ^= fieldPoly
+		}
+	}
+	return exp, log
+}()
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+fieldSize-1-int(gfLog[b])]
+}
+
+// gfPow returns α^e.
+func gfPow(e int) byte {
+	return gfExp[e%(fieldSize-1)]
+}
+
+// rsGenerator returns the generator polynomial (x - α)(x - α^2)...(x - α^n)
+// with its coefficients from the highest degree down.
+func rsGenerator(n int) []byte {
+	g := []byte{1}
+	for i := 1; i <= n; i++ {
+		next := make([]byte, len(g)+1)
+		for j, c := range g {
+			next[j] ^= c
+			next[j+1] ^= gfMul(c, gfPow(i))
+		}
+		g = next
+	}
+	return g
+}
+
+var generator = rsGenerator(paritySymbols)
+
+// rsEncode returns the parity of msg, the remainder of msg(x)·x^n divided
+// by the generator, with the coefficients of msg from the highest degree
+// down.
+func rsEncode(msg []byte) []byte {
+	parity := make([]byte, paritySymbols)
+	for _, m := range msg {
+		coef := m ^ parity[0]
+		copy(parity, parity[1:])
+		parity[len(parity)-1] = 0
+		for j := range parity {
+			parity[j] ^= gfMul(generator[j+1], coef)
+		}
+	}
+	return parity
+}
+
+// rsCorrect corrects up to paritySymbols/2 errors in the codeword c in
+// place and returns the indexes it changed. It reports false if c has
+// more errors than it can correct.
+func rsCorrect(c []byte) ([]int, bool) {
+	// The syndromes are the codeword evaluated at the roots of the
+	// generator, all zero for a valid codeword.
+	var s [paritySymbols]byte
+	valid := true
+	for j := range s {
+		x := gfPow(j + 1)
+		for _, v := range c {
+			s[j] = gfMul(s[j], x) ^ v
+		}
+		if s[j] != 0 {
+			valid = false
+		}
+	}
+	if valid {
+		return nil, true
+	}
+
+	// Berlekamp-Massey finds the error locator lambda, whose roots are
+	// the inverses of the error locations, lowest degree first.
+	lambda, prev := []byte{1}, []byte{1}
+	errors, shift, prevDiscrepancy := 0, 1, byte(1)
+	for n := 0; n < paritySymbols; n++ {
+		d := s[n]
+		for i := 1; i <= errors && i < len(lambda); i++ {
+			d ^= gfMul(lambda[i], s[n-i])
+		}
+		if d == 0 {
+			shift++
+			continue
+		}
+		next := append([]byte(nil), lambda...)
+		coef := gfDiv(d, prevDiscrepancy)
+		for i, p := range prev {
+			for len(next) <= i+shift {
+				next = append(next, 0)
+			}
+			next[i+shift] ^= gfMul(coef, p)
+		}
+		if 2*errors <= n {
+			prev, errors, prevDiscrepancy, shift = lambda, n+1-errors, d, 1
+		} else {
+			shift++
+		}
+		lambda = next
+	}
+	if 2*errors > paritySymbols {
+		return nil, false
+	}
+
+	// omega = s·lambda mod x^paritySymbols evaluates the error
+	// magnitudes with Forney's formula.
+	omega := make([]byte, paritySymbols)
+	for i := range omega {
+		for j := 0; j <= i && j < len(lambda); j++ {
+			omega[i] ^= gfMul(lambda[j], s[i-j])
+		}
+	}
+
+	// A Chien search tries every position: index i of c holds the
+	// coefficient of x^(len(c)-1-i).
+	var fixed []int
+	for i := range c {
+		e := len(c) - 1 - i
+		xInv := gfPow(fieldSize - 1 - e%(fieldSize-1))
+		if evalLow(lambda, xInv) != 0 {
+			continue
+		}
+		// lambda' has only the odd terms of lambda, in characteristic 2.
+		var derivative byte
+		for j := 1; j < len(lambda); j += 2 {
+			derivative ^= gfMul(lambda[j], gfPowOf(xInv, j-1))
+		}
+		if derivative == 0 {
+			return nil, false
+		}
+		c[i] ^= gfDiv(evalLow(omega, xInv), derivative)
+		fixed = append(fixed, i)
+	}
+	if len(fixed) != errors {
+		return nil, false
+	}
+	return fixed, true
+}
+
+// evalLow evaluates p, lowest degree first, at x.
+func evalLow(p []byte, x byte) byte {
+	var y byte
+	for i := len(p) - 1; i >= 0; i-- {
+		y = gfMul(y, x) ^ p[i]
+	}
+	return y
+}
+
+// gfPowOf returns x^n.
+func gfPowOf(x byte, n int) byte {
+	y := byte(1)
+	for i := 0; i < n; i++ {
+		y = gfMul(y, x)
+	}
+	return y
+}
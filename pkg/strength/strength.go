@@ -0,0 +1,493 @@
+// Package strength estimates how hard a passphrase is to guess.
+//
+// The estimate follows the approach of zxcvbn: the passphrase is split into
+// dictionary words, keyboard patterns, sequences and repetitions, each
+// costed by how many guesses an attacker who knows the pattern would need,
+// and whatever is left is costed as random characters. The embedded lists
+// of common passwords and English words are taken from zxcvbn
+// (https://github.com/dropbox/zxcvbn) under the MIT license, whose text is
+// in LICENSE.zxcvbn.
+package strength
+
+import (
+	_ "embed"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Warnings attached to the parts of a passphrase that make it guessable.
+const (
+	WarnCommonPassword  = "contains a common password"
+	WarnDictionaryWord  = "contains a dictionary word"
+	WarnKeyboardPattern = "contains a keyboard pattern"
+	WarnSequence        = "contains a sequence like abc or 123"
+	WarnRepetition      = "contains repeated characters"
+)
+
+// Result is the estimated strength of a passphrase.
+type Result struct {
+	// Entropy is the estimated number of bits of entropy, that is the base
+	// 2 logarithm of the number of guesses needed to find the passphrase.
+	Entropy float64
+
+	// Warnings lists the patterns found in the passphrase, in order of
+	// first appearance and without duplicates.
+	Warnings []string
+}
+
+// passwordList and wordList are the zxcvbn lists of common passwords and
+// English words, one per line from most to least common. See
+// LICENSE.zxcvbn.
+var (
+	//go:embed passwords.txt
+	passwordList string
+	//go:embed words.txt
+	wordList string
+
+	passwords = rankedList(passwordList)
+	words     = rankedList(wordList)
+)
+
+// rankedList maps each line of list to its 1-based position.
+func rankedList(list string) map[string]int {
+	lines := strings.Split(strings.TrimSpace(list), "\n")
+	ranks := make(map[string]int, len(lines))
+	for i, line := range lines {
+		if _, ok := ranks[line]; !ok {
+			ranks[line] = i + 1
+		}
+	}
+	return ranks
+}
+
+// match is a guessable part of the passphrase, covering runes [i, j).
+type match struct {
+	i, j    int
+	entropy float64
+	warning string
+}
+
+// Estimate returns the estimated strength of passphrase.
+func Estimate(passphrase string) Result {
+	s := []rune(passphrase)
+	if len(s) == 0 {
+		return Result{}
+	}
+
+	var matches []match
+	matches = append(matches, dictionaryMatches(s)...)
+	matches = append(matches, keyboardMatches(s)...)
+	matches = append(matches, sequenceMatches(s)...)
+	matches = append(matches, repeatMatches(s)...)
+
+	// Find the cheapest way to cover the passphrase with matches and
+	// random characters.
+	random := math.Log2(float64(cardinality(s)))
+	ending := make([][]*match, len(s)+1)
+	for k := range matches {
+		m := &matches[k]
+		ending[m.j] = append(ending[m.j], m)
+	}
+	best := make([]float64, len(s)+1)
+	via := make([]*match, len(s)+1)
+	for j := 1; j <= len(s); j++ {
+		best[j] = best[j-1] + random
+		for _, m := range ending[j] {
+			if best[m.i]+m.entropy < best[j] {
+				best[j] = best[m.i] + m.entropy
+				via[j] = m
+			}
+		}
+	}
+
+	var used []*match
+	for j := len(s); j > 0; {
+		if m := via[j]; m != nil {
+			used = append(used, m)
+			j = m.i
+		} else {
+			j--
+		}
+	}
+
+	result := Result{Entropy: best[len(s)]}
+	for k := len(used) - 1; k >= 0; k-- {
+		w := used[k].warning
+		if !contains(result.Warnings, w) {
+			result.Warnings = append(result.Warnings, w)
+		}
+	}
+	return result
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// cardinality is the size of the smallest set of characters, built from
+// the usual classes, that the passphrase could have been drawn from.
+func cardinality(s []rune) int {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < unicode.MaxASCII:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+
+	n := 0
+	if lower {
+		n += 26
+	}
+	if upper {
+		n += 26
+	}
+	if digit {
+		n += 10
+	}
+	if symbol {
+		n += 33
+	}
+	if other {
+		n += 100
+	}
+	return n
+}
+
+// leet undoes common character substitutions. '1' is tried as both 'i' and
+// 'l'.
+var leet = map[rune][]rune{
+	'4': {'a'}, '@': {'a'}, '8': {'b'}, '(': {'c'}, '3': {'e'}, '6': {'g'},
+	'1': {'i', 'l'}, '!': {'i'}, '|': {'i', 'l'}, '0': {'o'}, '$': {'s'},
+	'5': {'s'}, '7': {'t'}, '+': {'t'}, '2': {'z'},
+}
+
+const maxWordLength = 32
+
+func dictionaryMatches(s []rune) []match {
+	var matches []match
+	for i := range s {
+		for j := i + 3; j <= len(s) && j-i <= maxWordLength; j++ {
+			word := s[i:j]
+			for _, reversed := range []bool{false, true} {
+				candidate := word
+				extra := caseEntropy(word)
+				if reversed {
+					candidate = reverse(word)
+					extra++
+				}
+				for _, p := range unleet(candidate) {
+					entropy := extra + float64(p.substitutions)
+					if rank, ok := passwords[p.word]; ok {
+						matches = append(matches, match{i, j, math.Log2(float64(rank)) + entropy, WarnCommonPassword})
+					}
+					if rank, ok := words[p.word]; ok {
+						matches = append(matches, match{i, j, math.Log2(float64(rank)) + entropy, WarnDictionaryWord})
+					}
+				}
+			}
+		}
+	}
+	return matches
+}
+
+func reverse(s []rune) []rune {
+	r := make([]rune, len(s))
+	for i, c := range s {
+		r[len(s)-1-i] = c
+	}
+	return r
+}
+
+type spelling struct {
+	word          string
+	substitutions int
+}
+
+// unleet returns the lower-case spellings of s, first as written and then
+// with substitutions undone. Each substituted character costs a bit.
+func unleet(s []rune) []spelling {
+	word := strings.ToLower(string(s))
+	spellings := []spelling{{word, 0}}
+
+	variants := []string{""}
+	substitutions := 0
+	for _, r := range word {
+		subs, ok := leet[r]
+		if !ok {
+			for k := range variants {
+				variants[k] += string(r)
+			}
+			continue
+		}
+		substitutions++
+		next := make([]string, 0, len(variants)*len(subs))
+		for _, v := range variants {
+			for _, sub := range subs {
+				next = append(next, v+string(sub))
+			}
+		}
+		if len(next) > 16 {
+			next = next[:16]
+		}
+		variants = next
+	}
+	if substitutions > 0 {
+		for _, v := range variants {
+			spellings = append(spellings, spelling{v, substitutions})
+		}
+	}
+	return spellings
+}
+
+// caseEntropy is the cost of guessing which letters of s are upper case.
+// Capitalising the first letter or the whole word costs one bit.
+func caseEntropy(s []rune) float64 {
+	upper, lower := 0, 0
+	for _, r := range s {
+		if unicode.IsUpper(r) {
+			upper++
+		} else if unicode.IsLower(r) {
+			lower++
+		}
+	}
+	switch {
+	case upper == 0:
+		return 0
+	case lower == 0, upper == 1 && unicode.IsUpper(s[0]):
+		return 1
+	}
+	return math.Log2(combinations(upper+lower, min(upper, lower)))
+}
+
+// combinations returns the sum of n choose i for i up to k.
+func combinations(n, k int) float64 {
+	sum, c := 0.0, 1.0
+	for i := 0; i <= k; i++ {
+		sum += c
+		c = c * float64(n-i) / float64(i+1)
+	}
+	return sum
+}
+
+// keyboardRows lays out a US QWERTY keyboard, unshifted and shifted. Each
+// row is offset by half a key from the one above it.
+var keyboardRows = [2][4]string{
+	{"`1234567890-=", "qwertyuiop[]\\", "asdfghjkl;'", "zxcvbnm,./"},
+	{"~!@#$%^&*()_+", "QWERTYUIOP{}|", "ASDFGHJKL:\"", "ZXCVBNM<>?"},
+}
+
+type keyPosition struct {
+	row, col int
+	shifted  bool
+}
+
+var keyPositions = func() map[rune]keyPosition {
+	positions := make(map[rune]keyPosition)
+	for shift, rows := range keyboardRows {
+		for row, keys := range rows {
+			for col, key := range keys {
+				positions[key] = keyPosition{row, col, shift == 1}
+			}
+		}
+	}
+	return positions
+}()
+
+const (
+	keyboardKeys   = 47
+	keyboardDegree = 4.6 // average number of neighbours of a key
+)
+
+// keyDirection returns which neighbour of a the key b is, or -1.
+func keyDirection(a, b rune) int {
+	pa, okA := keyPositions[a]
+	pb, okB := keyPositions[b]
+	if !okA || !okB {
+		return -1
+	}
+	dr, dc := pb.row-pa.row, pb.col-pa.col
+	for d, n := range [6][2]int{{0, -1}, {0, 1}, {-1, 0}, {-1, 1}, {1, -1}, {1, 0}} {
+		if dr == n[0] && dc == n[1] {
+			return d
+		}
+	}
+	return -1
+}
+
+// keyboardMatches finds runs of at least three adjacent keys, such as
+// "qwerty" or "zxcvb".
+func keyboardMatches(s []rune) []match {
+	var matches []match
+	for i := 0; i < len(s)-2; {
+		j, turns, last := i+1, 0, -1
+		for j < len(s) {
+			d := keyDirection(s[j-1], s[j])
+			if d < 0 {
+				break
+			}
+			if d != last {
+				turns++
+				last = d
+			}
+			j++
+		}
+		if j-i >= 3 {
+			matches = append(matches, match{i, j, keyboardEntropy(s[i:j], turns), WarnKeyboardPattern})
+			i = j
+		} else {
+			i++
+		}
+	}
+	return matches
+}
+
+// keyboardEntropy counts the keyboard patterns no longer than s with at
+// most the same number of turns, as zxcvbn does.
+func keyboardEntropy(s []rune, turns int) float64 {
+	guesses := 0.0
+	for n := 2; n <= len(s); n++ {
+		for t := 1; t <= min(turns, n-1); t++ {
+			guesses += binomial(n-1, t-1) * keyboardKeys * math.Pow(keyboardDegree, float64(t))
+		}
+	}
+	entropy := math.Log2(guesses)
+
+	shifted := 0
+	for _, r := range s {
+		if keyPositions[r].shifted {
+			shifted++
+		}
+	}
+	if shifted > 0 && shifted < len(s) {
+		entropy += math.Log2(combinations(len(s), min(shifted, len(s)-shifted)))
+	} else if shifted > 0 {
+		entropy++
+	}
+	return entropy
+}
+
+func binomial(n, k int) float64 {
+	c := 1.0
+	for i := 0; i < k; i++ {
+		c = c * float64(n-i) / float64(i+1)
+	}
+	return c
+}
+
+// sequenceMatches finds runs of at least three letters or digits that step
+// by one, such as "abc", "987" or "XYZ".
+func sequenceMatches(s []rune) []match {
+	var matches []match
+	for i := 0; i < len(s)-2; {
+		step := s[i+1] - s[i]
+		j := i + 1
+		if (step == 1 || step == -1) && sameClass(s[i], s[j]) {
+			for j < len(s) && s[j]-s[j-1] == step && sameClass(s[j-1], s[j]) {
+				j++
+			}
+		}
+		if j-i >= 3 {
+			entropy := math.Log2(float64(j - i))
+			switch {
+			case s[i] == 'a' || s[i] == 'A' || s[i] == '0' || s[i] == '1':
+				entropy++
+			case unicode.IsDigit(s[i]):
+				entropy += math.Log2(10)
+			default:
+				entropy += math.Log2(26)
+			}
+			if unicode.IsUpper(s[i]) {
+				entropy++
+			}
+			if step < 0 {
+				entropy++
+			}
+			matches = append(matches, match{i, j, entropy, WarnSequence})
+			i = j - 1
+		} else {
+			i++
+		}
+	}
+	return matches
+}
+
+func sameClass(a, b rune) bool {
+	switch {
+	case a >= 'a' && a <= 'z':
+		return b >= 'a' && b <= 'z'
+	case a >= 'A' && a <= 'Z':
+		return b >= 'A' && b <= 'Z'
+	case a >= '0' && a <= '9':
+		return b >= '0' && b <= '9'
+	}
+	return false
+}
+
+// maxRepeatUnit bounds the length of the repeated group looked for.
+const maxRepeatUnit = 64
+
+// repeatMatches finds a character or a group of characters repeated
+// immediately after itself, such as "aaa" or "abcabc". Only the longest
+// repetition starting at each position is reported.
+func repeatMatches(s []rune) []match {
+	var matches []match
+	for size := 1; size <= maxRepeatUnit && 2*size <= len(s); size++ {
+		// run counts how many of the following runes equal the rune size
+		// positions later.
+		for i := 0; i+size < len(s); {
+			run := 0
+			for i+size+run < len(s) && s[i+run] == s[i+size+run] {
+				run++
+			}
+			count := run/size + 1
+			if count < 2 || (size == 1 && count < 3) || isRepeat(s[i:i+size]) {
+				i += max(run, 1)
+				continue
+			}
+
+			var entropy float64
+			if size == 1 {
+				entropy = math.Log2(float64(cardinality(s[i : i+1])))
+			} else {
+				entropy = Estimate(string(s[i : i+size])).Entropy
+			}
+			entropy += math.Log2(float64(count))
+			matches = append(matches, match{i, i + size*count, entropy, WarnRepetition})
+			i += max(run, 1)
+		}
+	}
+	return matches
+}
+
+// isRepeat reports whether unit is itself a repetition of a shorter group,
+// in which case that group is reported instead.
+func isRepeat(unit []rune) bool {
+	for size := 1; size < len(unit); size++ {
+		if len(unit)%size != 0 {
+			continue
+		}
+		repeat := true
+		for k := size; k < len(unit) && repeat; k++ {
+			repeat = unit[k] == unit[k-size]
+		}
+		if repeat {
+			return true
+		}
+	}
+	return false
+}
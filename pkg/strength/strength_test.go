@@ -0,0 +1,83 @@
+package strength
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEstimate(t *testing.T) {
+	testCases := []struct {
+		passphrase string
+		maxEntropy float64
+		warning    string
+	}{
+		{"password", 1, WarnCommonPassword},
+		{"P@ssw0rd", 10, WarnCommonPassword},
+		{"Password1", 10, WarnCommonPassword},
+		{"drowssap", 10, WarnCommonPassword},
+		{"qwertyuiop", 15, WarnCommonPassword},
+		{"zxcvfdsa", 20, WarnKeyboardPattern},
+		{"abcdefghij", 10, WarnSequence},
+		{"9876543210", 10, WarnSequence},
+		{"aaaaaaaaaaaaaaaa", 10, WarnRepetition},
+		{"xkcdxkcdxkcdxkcd", 30, WarnRepetition},
+		{"horizontal", 20, WarnDictionaryWord},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.passphrase, func(t *testing.T) {
+			result := Estimate(tc.passphrase)
+			if result.Entropy > tc.maxEntropy {
+				t.Errorf("Expected at most %.1f bits, got %.1f", tc.maxEntropy, result.Entropy)
+			}
+			if !contains(result.Warnings, tc.warning) {
+				t.Errorf("Expected warning %q, got %v", tc.warning, result.Warnings)
+			}
+		})
+	}
+}
+
+func TestEstimateStrong(t *testing.T) {
+	testCases := []struct {
+		passphrase string
+		minEntropy float64
+	}{
+		{"Xk9#mP2q", 50},
+		{"YWJjMTIzIT8kKiYoKSctPUB+", 100},
+		{"correct horse battery staple", 50},
+		{"zq7Rw!vB3m@Lp", 70},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.passphrase, func(t *testing.T) {
+			if result := Estimate(tc.passphrase); result.Entropy < tc.minEntropy {
+				t.Errorf("Expected at least %.1f bits, got %.1f (%v)", tc.minEntropy, result.Entropy, result.Warnings)
+			}
+		})
+	}
+}
+
+func TestEstimateBounds(t *testing.T) {
+	if result := Estimate(""); result.Entropy != 0 || len(result.Warnings) != 0 {
+		t.Errorf("Estimate(\"\") = %+v", result)
+	}
+
+	// A random string can never cost more than its characters guessed one
+	// at a time.
+	s := "h3Q;vT8#kP"
+	if result := Estimate(s); result.Entropy > float64(len(s))*7 {
+		t.Errorf("Estimate(%q) = %.1f bits, more than brute force", s, result.Entropy)
+	}
+
+	// Long input must not take quadratic time in the dictionary.
+	Estimate(strings.Repeat("correct horse ", 200))
+}
+
+func TestWordLists(t *testing.T) {
+	if passwords["password"] != 1 {
+		t.Errorf("Expected \"password\" to be the most common password, got rank %d", passwords["password"])
+	}
+	if len(words) < 10000 {
+		t.Errorf("Expected at least 10000 words, got %d", len(words))
+	}
+}
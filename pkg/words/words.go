@@ -0,0 +1,239 @@
+// Package words encodes data as words of the BIP 39 English wordlist, for
+// keys and short messages that are read aloud or typed in.
+//
+// Each 2 bytes of data become a group of 2 words, which carry 22 bits: the
+// 16 bits of the data and a 6-bit check of the data and the position of the
+// group. A misheard word or a swapped group fails the check with
+// probability 63/64 and is reported with its position. A final odd byte
+// becomes a single word with a 3-bit check.
+//
+// As in BIP 39, a word is recognized by its first 4 letters, in any case,
+// so abbreviations and misspellings after the fourth letter are accepted.
+package words
+
+import (
+	"bufio"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// english is the BIP 39 English wordlist
+// (https://github.com/bitcoin/bips/blob/master/bip-0039/english.txt), one
+// word per line in the order of their values.
+//
+//go:embed english.txt
+var english string
+
+const (
+	// prefixLength is the number of letters that identify a word.
+	prefixLength = 4
+
+	// wordsPerLine is the number of words the encoder writes on a line.
+	wordsPerLine = 8
+)
+
+var (
+	wordlist = strings.Fields(english)
+
+	// index maps the first prefixLength letters of each word, or all of a
+	// shorter word, to its value.
+	index = func() map[string]uint16 {
+		m := make(map[string]uint16, len(wordlist))
+		for i, w := range wordlist {
+			m[w[:min(len(w), prefixLength)]] = uint16(i)
+		}
+		return m
+	}()
+)
+
+// lookup returns the value of word.
+func lookup(word string) (uint16, bool) {
+	word = strings.ToLower(word)
+	v, ok := index[word[:min(len(word), prefixLength)]]
+	return v, ok
+}
+
+// check returns the check bits of the data of the group numbered group,
+// in the high bits of a byte.
+func check(group int, data []byte) byte {
+	h := sha256.New()
+	binary.Write(h, binary.BigEndian, uint32(group))
+	h.Write(data)
+	return h.Sum(nil)[0]
+}
+
+// CorruptInputError reports the first word, counting from 1, that could
+// not be decoded.
+type CorruptInputError struct {
+	Word   int
+	Reason string
+}
+
+func (e *CorruptInputError) Error() string {
+	return fmt.Sprintf("%s at word %d", e.Reason, e.Word)
+}
+
+// Encode returns the words of data, separated by spaces and with a line
+// break after every 8 words.
+func Encode(data []byte) string {
+	var b strings.Builder
+	w := NewEncoder(&b)
+	w.Write(data) // a strings.Builder does not fail
+	w.Close()
+	return b.String()
+}
+
+// Decode returns the data of the words in s, which may be separated by any
+// whitespace.
+func Decode(s string) ([]byte, error) {
+	return io.ReadAll(NewDecoder(strings.NewReader(s)))
+}
+
+// NewEncoder returns a writer that writes the words of the data written to
+// it to w. Close writes the word of a final odd byte; it does not close w.
+func NewEncoder(w io.Writer) io.WriteCloser {
+	return &encoder{w: w}
+}
+
+type encoder struct {
+	w          io.Writer
+	pending    byte // the first byte of an incomplete group
+	hasPending bool
+	group      int // groups written
+	words      int // words written
+	out        []byte
+}
+
+func (e *encoder) Write(p []byte) (int, error) {
+	n := len(p)
+	e.out = e.out[:0]
+	if e.hasPending && len(p) > 0 {
+		e.appendGroup([]byte{e.pending, p[0]})
+		e.hasPending = false
+		p = p[1:]
+	}
+	for ; len(p) >= 2; p = p[2:] {
+		e.appendGroup(p[:2])
+	}
+	if len(p) == 1 {
+		e.pending, e.hasPending = p[0], true
+	}
+	if _, err := e.w.Write(e.out); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (e *encoder) Close() error {
+	if !e.hasPending {
+		return nil
+	}
+	e.out = e.out[:0]
+	e.appendGroup([]byte{e.pending})
+	e.hasPending = false
+	_, err := e.w.Write(e.out)
+	return err
+}
+
+// appendGroup appends the words of a group of 1 or 2 bytes to e.out.
+func (e *encoder) appendGroup(data []byte) {
+	sum := check(e.group, data)
+	e.group++
+	if len(data) == 1 {
+		e.appendWord(uint32(data[0])<<3 | uint32(sum>>5))
+		return
+	}
+	v := uint32(data[0])<<14 | uint32(data[1])<<6 | uint32(sum>>2)
+	e.appendWord(v >> 11)
+	e.appendWord(v & 0x7FF)
+}
+
+func (e *encoder) appendWord(v uint32) {
+	if e.words > 0 {
+		if e.words%wordsPerLine == 0 {
+			e.out = append(e.out, '\n')
+		} else {
+			e.out = append(e.out, ' ')
+		}
+	}
+	e.out = append(e.out, wordlist[v]...)
+	e.words++
+}
+
+// NewDecoder returns a reader of the data of the words read from r. It
+// returns a *CorruptInputError for a word it does not know or a group that
+// fails its check.
+func NewDecoder(r io.Reader) io.Reader {
+	s := bufio.NewScanner(r)
+	s.Split(bufio.ScanWords)
+	return &decoder{s: s}
+}
+
+type decoder struct {
+	s     *bufio.Scanner
+	words int // words read
+	group int // groups decoded
+	buf   [2]byte
+	out   []byte // decoded but not yet returned
+	err   error  // returned once out is empty
+}
+
+func (d *decoder) Read(p []byte) (int, error) {
+	for len(d.out) == 0 && d.err == nil {
+		d.err = d.next()
+	}
+	if len(d.out) > 0 {
+		n := copy(p, d.out)
+		d.out = d.out[n:]
+		return n, nil
+	}
+	return 0, d.err
+}
+
+// next decodes the next group, or returns io.EOF at the end of the input.
+func (d *decoder) next() error {
+	var v uint32
+	n := 0
+	for n < 2 && d.s.Scan() {
+		if err := d.s.Err(); err != nil {
+			// The last word before a read error may be cut short.
+			return err
+		}
+		d.words++
+		w, ok := lookup(d.s.Text())
+		if !ok {
+			return &CorruptInputError{d.words, fmt.Sprintf("unknown word %q", d.s.Text())}
+		}
+		v = v<<11 | uint32(w)
+		n++
+	}
+	if err := d.s.Err(); err != nil {
+		return err
+	}
+
+	var data []byte
+	var sum byte
+	switch n {
+	case 0:
+		return io.EOF
+	case 1:
+		data = append(d.buf[:0], byte(v>>3))
+		sum = byte(v&0x7) << 5
+		if check(d.group, data)&0xE0 != sum {
+			return &CorruptInputError{d.words, "failed check of the last word"}
+		}
+	case 2:
+		data = append(d.buf[:0], byte(v>>14), byte(v>>6))
+		sum = byte(v&0x3F) << 2
+		if check(d.group, data)&0xFC != sum {
+			return &CorruptInputError{d.words - 1, "failed check of the group of 2 words"}
+		}
+	}
+	d.group++
+	d.out = data
+	return nil
+}
@@ -0,0 +1,125 @@
+package words
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestWordlist(t *testing.T) {
+	if len(wordlist) != 2048 || len(index) != 2048 {
+		t.Fatalf("Wordlist has %d words and %d prefixes, want 2048", len(wordlist), len(index))
+	}
+	if wordlist[0] != "abandon" || wordlist[2047] != "zoo" {
+		t.Errorf("Wordlist runs from %q to %q", wordlist[0], wordlist[2047])
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for size := 0; size < 40; size++ {
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(i*131 + 7)
+		}
+		encoded := Encode(data)
+		if n := len(strings.Fields(encoded)); n != size {
+			t.Errorf("Encoding %d bytes gave %d words", size, n)
+		}
+		for _, line := range strings.Split(encoded, "\n") {
+			if n := len(strings.Fields(line)); n > wordsPerLine {
+				t.Errorf("Line %q has %d words", line, n)
+			}
+		}
+
+		decoded, err := Decode(encoded)
+		if err != nil || !bytes.Equal(decoded, data) {
+			t.Errorf("Decode(%q) = %v, %v, want %v", encoded, decoded, err, data)
+		}
+
+		var b strings.Builder
+		w := NewEncoder(&b)
+		for _, c := range data {
+			w.Write([]byte{c})
+		}
+		w.Close()
+		if b.String() != encoded {
+			t.Errorf("Encoding a byte at a time gave %q, want %q", b.String(), encoded)
+		}
+		r := NewDecoder(iotest.OneByteReader(strings.NewReader(encoded)))
+		if err := iotest.TestReader(r, data); err != nil {
+			t.Errorf("Decoding a byte at a time: %v", err)
+		}
+	}
+}
+
+func TestDecodeLeniency(t *testing.T) {
+	data := []byte("key!!")
+	encoded := Encode(data)
+	var abbreviated []string
+	for _, w := range strings.Fields(encoded) {
+		abbreviated = append(abbreviated, w[:min(len(w), prefixLength)])
+	}
+
+	for _, s := range []string{
+		strings.ToUpper(encoded),
+		strings.Join(abbreviated, "\t"),
+		"\n  " + strings.ReplaceAll(encoded, "\n", " ") + "\r\n",
+	} {
+		if decoded, err := Decode(s); err != nil || !bytes.Equal(decoded, data) {
+			t.Errorf("Decode(%q) = %q, %v", s, decoded, err)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	data := make([]byte, 32)
+	for i := range data {
+		data[i] = byte(i*131 + 7)
+	}
+	words := strings.Fields(Encode(data))
+
+	testCases := []struct {
+		name  string
+		input []string
+		word  int
+	}{
+		{"UnknownWord", append([]string{"xylophone"}, words[1:]...), 1},
+		{"SwappedGroups", append([]string{words[2], words[3], words[0], words[1]}, words[4:]...), 1},
+		{"MissingGroup", words[2:], 1},
+		{"MissingWord", words[1:], 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Decode(strings.Join(tc.input, " "))
+			var corrupt *CorruptInputError
+			if !errors.As(err, &corrupt) || corrupt.Word != tc.word {
+				t.Errorf("Expected a CorruptInputError at word %d, got %v", tc.word, err)
+			}
+		})
+	}
+
+	// Replacing a word by any other word is almost always caught by the
+	// check of its group.
+	missed := 0
+	for i := 0; i < 4; i++ {
+		for _, replacement := range wordlist {
+			if replacement == words[i] {
+				continue
+			}
+			input := append([]string(nil), words...)
+			input[i] = replacement
+			var corrupt *CorruptInputError
+			if _, err := Decode(strings.Join(input, " ")); err == nil {
+				missed++
+			} else if !errors.As(err, &corrupt) || corrupt.Word != i/2*2+1 {
+				t.Fatalf("Replacing word %d with %q gave %v", i+1, replacement, err)
+			}
+		}
+	}
+	if missed > 4*2047/32 {
+		t.Errorf("%d of %d wrong words were not caught", missed, 4*2047)
+	}
+}
@@ -0,0 +1,164 @@
+package argon2aes
+
+import (
+	"context"
+	"crypto/cipher"
+	"fmt"
+	"io"
+	"sync"
+)
+
+type readerAt struct {
+	r         io.ReaderAt
+	aead      cipher.AEAD
+	chunkSize int64
+	chunks    int64
+	stored    int64
+	size      int64
+
+	mu     sync.Mutex
+	cached int64
+	buf    []byte
+}
+
+// OpenReaderAt authenticates the header of a stream written by NewWriter
+// and returns a ReaderAt over its plaintext along with the plaintext size.
+// Each ReadAt decrypts only the chunks covering the requested range. The
+// returned ReaderAt is safe for concurrent use.
+func OpenReaderAt(r io.ReaderAt, size int64, password []byte) (io.ReaderAt, int64, error) {
+	b := make([]byte, headerLength)
+	if n, err := r.ReadAt(b, 0); n < len(b) {
+		if err == io.EOF {
+			return nil, 0, corruptf("invalid stream header")
+		}
+		return nil, 0, err
+	}
+
+	h, keys, err := openHeader(context.Background(), b, password, newOptions(nil))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if h.compress != uint8(CompressNone) {
+		return nil, 0, fmt.Errorf("compressed streams do not support random access")
+	}
+
+	chunkSize := int64(h.chunkSize)
+	payload := size - headerLength
+	if payload < tagLength {
+		return nil, 0, corruptf("ciphertext too short")
+	}
+	chunks := (payload + chunkSize + tagLength - 1) / (chunkSize + tagLength)
+	if chunks > 1<<32 {
+		return nil, 0, corruptf("stream too long")
+	}
+	lastSize := payload - (chunks-1)*(chunkSize+tagLength)
+	if lastSize < tagLength {
+		return nil, 0, corruptf("ciphertext too short")
+	}
+
+	ra := &readerAt{
+		r:         r,
+		aead:      keys.aead,
+		chunkSize: chunkSize,
+		chunks:    chunks,
+		stored:    (chunks-1)*chunkSize + lastSize - tagLength,
+		cached:    -1,
+	}
+	ra.size = ra.stored
+
+	// Authenticating the final chunk up front detects truncation, which
+	// would otherwise go unnoticed until the end is read.
+	if _, err := ra.chunk(chunks - 1); err != nil {
+		return nil, 0, err
+	}
+	if h.padding != padNone {
+		if ra.size, err = ra.unpaddedSize(); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return ra, ra.size, nil
+}
+
+// unpaddedSize finds the padding marker by scanning back from the final
+// chunk, since padding may span several chunks.
+func (ra *readerAt) unpaddedSize() (int64, error) {
+	for i := ra.chunks - 1; i >= 0; i-- {
+		plaintext, err := ra.chunk(i)
+		if err != nil {
+			return 0, err
+		}
+		for j := len(plaintext) - 1; j >= 0; j-- {
+			if plaintext[j] == 0x80 {
+				return i*ra.chunkSize + int64(j), nil
+			} else if plaintext[j] != 0 {
+				return 0, corruptf("invalid padding")
+			}
+		}
+	}
+	return 0, corruptf("invalid padding")
+}
+
+func (ra *readerAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset")
+	}
+	if off >= ra.size {
+		return 0, io.EOF
+	}
+
+	var eof error
+	if int64(len(p)) > ra.size-off {
+		p, eof = p[:ra.size-off], io.EOF
+	}
+
+	n := 0
+	for n < len(p) {
+		plaintext, err := ra.chunk(off / ra.chunkSize)
+		if err != nil {
+			return n, err
+		}
+		m := copy(p[n:], plaintext[off%ra.chunkSize:])
+		n += m
+		off += int64(m)
+	}
+	return n, eof
+}
+
+// chunk returns the plaintext of chunk i. The most recently decrypted chunk
+// is kept so that small sequential reads do not decrypt it repeatedly.
+func (ra *readerAt) chunk(i int64) ([]byte, error) {
+	ra.mu.Lock()
+	if ra.cached == i {
+		buf := ra.buf
+		ra.mu.Unlock()
+		return buf, nil
+	}
+	ra.mu.Unlock()
+
+	last := i == ra.chunks-1
+	size := ra.chunkSize + tagLength
+	if last {
+		size = ra.stored - i*ra.chunkSize + tagLength
+	}
+
+	ciphertext := make([]byte, size)
+	if n, err := ra.r.ReadAt(ciphertext, headerLength+i*(ra.chunkSize+tagLength)); n < len(ciphertext) {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	nonce := chunkNonce(make([]byte, ra.aead.NonceSize()), uint32(i), last)
+	plaintext, err := ra.aead.Open(ciphertext[:0], nonce, ciphertext, nil)
+	if err != nil {
+		return nil, corruptf("corrupt chunk %d: %w", i, err)
+	}
+
+	ra.mu.Lock()
+	ra.cached, ra.buf = i, plaintext
+	ra.mu.Unlock()
+	return plaintext, nil
+}
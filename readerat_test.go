@@ -0,0 +1,116 @@
+package argon2aes
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// countingReaderAt records how many bytes were read through it.
+type countingReaderAt struct {
+	r *bytes.Reader
+	n int64
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := c.r.ReadAt(p, off)
+	c.n += int64(n)
+	return n, err
+}
+
+func TestOpenReaderAt(t *testing.T) {
+	password := []byte("password")
+	data := make([]byte, 10000)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	encrypted := encryptStream(t, data, password, WithChunkSize(256))
+
+	ra, size, err := OpenReaderAt(bytes.NewReader(encrypted), int64(len(encrypted)), password)
+	if err != nil {
+		t.Fatalf("OpenReaderAt failed: %v", err)
+	}
+	if size != int64(len(data)) {
+		t.Fatalf("Expected size %d, got %d", len(data), size)
+	}
+
+	testCases := []struct {
+		name string
+		off  int64
+		n    int
+	}{
+		{"Start", 0, 10},
+		{"WithinChunk", 300, 100},
+		{"AcrossChunks", 250, 600},
+		{"End", 9990, 10},
+		{"All", 0, 10000},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := make([]byte, tc.n)
+			n, err := ra.ReadAt(p, tc.off)
+			if err != nil {
+				t.Fatalf("ReadAt failed: %v", err)
+			}
+			if n != tc.n || !bytes.Equal(p, data[tc.off:tc.off+int64(tc.n)]) {
+				t.Errorf("ReadAt(%d, %d) returned wrong data", tc.off, tc.n)
+			}
+		})
+	}
+
+	p := make([]byte, 20)
+	n, err := ra.ReadAt(p, 9990)
+	if n != 10 || err != io.EOF {
+		t.Errorf("Expected 10 bytes and io.EOF past the end, got %d and %v", n, err)
+	}
+	if _, err := ra.ReadAt(p, size); err != io.EOF {
+		t.Errorf("Expected io.EOF at the end, got %v", err)
+	}
+}
+
+func TestOpenReaderAtPartialDecrypt(t *testing.T) {
+	password := []byte("password")
+	data := bytes.Repeat([]byte("E"), 100*1024)
+	encrypted := encryptStream(t, data, password, WithChunkSize(1024))
+
+	c := &countingReaderAt{r: bytes.NewReader(encrypted)}
+	ra, _, err := OpenReaderAt(c, int64(len(encrypted)), password)
+	if err != nil {
+		t.Fatalf("OpenReaderAt failed: %v", err)
+	}
+
+	c.n = 0
+	p := make([]byte, 100)
+	if _, err := ra.ReadAt(p, 50*1024+500); err != nil {
+		t.Fatalf("ReadAt failed: %v", err)
+	}
+	if c.n != 1024+tagLength {
+		t.Errorf("Expected a single chunk to be read, read %d bytes", c.n)
+	}
+}
+
+func TestOpenReaderAtErrors(t *testing.T) {
+	password := []byte("password")
+	encrypted := encryptStream(t, bytes.Repeat([]byte("F"), 1000), password, WithChunkSize(256))
+
+	testCases := []struct {
+		name     string
+		data     []byte
+		password []byte
+	}{
+		{"WrongPassword", encrypted, []byte("wrong password")},
+		{"Truncated", encrypted[:len(encrypted)-300], password},
+		{"ShortHeader", encrypted[:headerLength-1], password},
+		{"Legacy", make([]byte, 100), password},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := OpenReaderAt(bytes.NewReader(tc.data), int64(len(tc.data)), tc.password)
+			if err == nil {
+				t.Error("Expected an error, but got none")
+			}
+		})
+	}
+}
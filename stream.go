@@ -0,0 +1,649 @@
+package argon2aes
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"runtime"
+	"slices"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// The stream format starts with a fixed-size header followed by the payload
+// split into chunks of chunkSize plaintext bytes, each sealed independently
+// with AES-256-GCM. Every chunk can therefore be authenticated and decrypted
+// on its own, which is what makes random access possible.
+//
+//	magic      [8]byte
+//	version    uint8
+//	cipher     uint8
+//	kdf        uint8
+//	time       uint32
+//	memory     uint32
+//	threads    uint8
+//	chunkSize  uint32
+//	padding    uint8
+//	compress   uint8
+//	reserved   [2]byte
+//	salt       [32]byte
+//	seed       [16]byte
+//	mac        [32]byte   HMAC-SHA256 over all preceding header bytes
+//
+// Chunk i is sealed with the nonce 0x00*7 || uint32(i) || last, where last is
+// 1 for the final chunk and 0 otherwise, so truncation and reordering are
+// detected. The final chunk may be short or empty but is always present.
+const (
+	DefaultChunkSize = 64 * 1024
+	MaxChunkSize     = 16 * 1024 * 1024
+
+	streamVersion   = 1
+	cipherAESGCM    = 1
+	kdfArgon2id     = 1
+	seedLength      = 16
+	macLength       = sha256.Size
+	tagLength       = 16
+	headerMACOffset = 8 + 3 + 9 + 4 + 4 + saltLength + seedLength
+	headerLength    = headerMACOffset + macLength
+
+	// Limits on the key derivation parameters accepted from a header, so
+	// that a crafted file cannot make a reader spin for hours.
+	maxTime   = 64
+	maxMemory = 4 * 1024 * 1024
+)
+
+// magic identifies the stream format. Like the PNG signature it contains
+// bytes that are mangled by text-mode transfers.
+var magic = []byte("\x89A2A\r\n\x1a\n")
+
+// Magic returns the signature every stream starts with, by which other
+// tools can recognize encrypted data. Files written by versions before the
+// stream format have no signature.
+func Magic() []byte {
+	return bytes.Clone(magic)
+}
+
+// An Option configures NewWriter and NewReader.
+type Option func(*options)
+
+type options struct {
+	chunkSize int
+	jobs      int
+	padding   Padding
+	compress  Compression
+	maxSize   int64
+	keyFunc   KeyFunc
+	keyParams *KeyParams
+}
+
+// WithChunkSize sets the plaintext size of each chunk written by NewWriter.
+// Readers take the chunk size from the stream header.
+func WithChunkSize(size int) Option {
+	return func(o *options) {
+		o.chunkSize = size
+	}
+}
+
+// WithJobs sets how many chunks are encrypted or decrypted concurrently.
+// Chunks are still written and returned in order, and no more than jobs
+// chunks are buffered at a time. A value below 1 uses one job per CPU.
+func WithJobs(jobs int) Option {
+	return func(o *options) {
+		if jobs < 1 {
+			jobs = runtime.NumCPU()
+		}
+		o.jobs = jobs
+	}
+}
+
+// WithPadding pads the plaintext with the given scheme to hide its exact
+// length. Readers detect padding from the stream header.
+func WithPadding(p Padding) Option {
+	return func(o *options) {
+		o.padding = p
+	}
+}
+
+// WithCompression compresses the plaintext before it is encrypted. Readers
+// detect compression from the stream header.
+func WithCompression(c Compression) Option {
+	return func(o *options) {
+		o.compress = c
+	}
+}
+
+// WithMaxSize makes NewReader and Decrypt fail once more than size bytes of
+// plaintext have been produced, which guards against decompression bombs.
+// A size of 0, the default, means no limit; a negative size is an error.
+func WithMaxSize(size int64) Option {
+	return func(o *options) {
+		o.maxSize = size
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		chunkSize: DefaultChunkSize,
+		jobs:      1,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+type header struct {
+	version   uint8
+	cipher    uint8
+	kdf       uint8
+	time      uint32
+	memory    uint32
+	threads   uint8
+	chunkSize uint32
+	padding   uint8
+	compress  uint8
+	salt      [saltLength]byte
+	seed      [seedLength]byte
+}
+
+func (h *header) marshal() []byte {
+	b := make([]byte, 0, headerLength)
+	b = append(b, magic...)
+	b = append(b, h.version, h.cipher, h.kdf)
+	b = binary.BigEndian.AppendUint32(b, h.time)
+	b = binary.BigEndian.AppendUint32(b, h.memory)
+	b = append(b, h.threads)
+	b = binary.BigEndian.AppendUint32(b, h.chunkSize)
+	b = append(b, h.padding, h.compress, 0, 0)
+	b = append(b, h.salt[:]...)
+	b = append(b, h.seed[:]...)
+	return b
+}
+
+func parseHeader(b []byte) (*header, error) {
+	if len(b) < headerLength || !bytes.HasPrefix(b, magic) {
+		return nil, corruptf("invalid stream header")
+	}
+	b = b[len(magic):]
+
+	h := &header{
+		version:   b[0],
+		cipher:    b[1],
+		kdf:       b[2],
+		time:      binary.BigEndian.Uint32(b[3:]),
+		memory:    binary.BigEndian.Uint32(b[7:]),
+		threads:   b[11],
+		chunkSize: binary.BigEndian.Uint32(b[12:]),
+		padding:   b[16],
+		compress:  b[17],
+	}
+	copy(h.salt[:], b[20:])
+	copy(h.seed[:], b[20+saltLength:])
+
+	if h.version != streamVersion {
+		return nil, corruptf("unsupported stream version %d", h.version)
+	}
+	if h.cipher != cipherAESGCM {
+		return nil, corruptf("unsupported cipher %d", h.cipher)
+	}
+	if h.kdf != kdfArgon2id {
+		return nil, corruptf("unsupported key derivation function %d", h.kdf)
+	}
+	if h.time == 0 || h.time > maxTime || h.threads == 0 ||
+		h.memory < 8*uint32(h.threads) || h.memory > maxMemory {
+		return nil, corruptf("invalid key derivation parameters")
+	}
+	if h.chunkSize == 0 || h.chunkSize > MaxChunkSize {
+		return nil, corruptf("invalid chunk size %d", h.chunkSize)
+	}
+	if h.padding > padBlock {
+		return nil, corruptf("unsupported padding %d", h.padding)
+	}
+	if h.compress > uint8(CompressGzip) {
+		return nil, corruptf("unsupported compression %d", h.compress)
+	}
+	if !bytes.Equal(b[18:20], []byte{0, 0}) {
+		return nil, corruptf("invalid stream header")
+	}
+	return h, nil
+}
+
+// streamKeys holds the keys derived for a single stream.
+type streamKeys struct {
+	mac  []byte
+	aead cipher.AEAD
+}
+
+func (h *header) keyParams() KeyParams {
+	return KeyParams{Salt: h.salt[:], Time: h.time, Memory: h.memory, Threads: h.threads}
+}
+
+// deriveStreamKeys derives the keys of a stream from its master key and
+// seed. Obtaining the master key usually means running Argon2id, which
+// cannot be interrupted, so ctx is only checked before and after it.
+func deriveStreamKeys(ctx context.Context, h *header, password []byte, o *options) (*streamKeys, error) {
+	ikm, err := o.masterKey(ctx, password, h.keyParams())
+	if err != nil {
+		return nil, err
+	}
+
+	macKey := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, ikm, h.seed[:], []byte("a2a header")), macKey); err != nil {
+		return nil, err
+	}
+	payloadKey := make([]byte, keyLength)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, ikm, h.seed[:], []byte("a2a payload")), payloadKey); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(payloadKey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &streamKeys{mac: macKey, aead: gcm}, nil
+}
+
+func (k *streamKeys) headerMAC(b []byte) []byte {
+	m := hmac.New(sha256.New, k.mac)
+	m.Write(b[:headerMACOffset])
+	return m.Sum(nil)
+}
+
+// openHeader parses and authenticates a raw header.
+func openHeader(ctx context.Context, b []byte, password []byte, o *options) (*header, *streamKeys, error) {
+	h, err := parseHeader(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	keys, err := deriveStreamKeys(ctx, h, password, o)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !hmac.Equal(keys.headerMAC(b), b[headerMACOffset:headerLength]) {
+		return nil, nil, fmt.Errorf("%w or corrupt header", ErrWrongPassword)
+	}
+	return h, keys, nil
+}
+
+func chunkNonce(nonce []byte, index uint32, last bool) []byte {
+	clear(nonce)
+	binary.BigEndian.PutUint32(nonce[7:], index)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+type writer struct {
+	ctx       context.Context
+	w         io.Writer
+	aead      cipher.AEAD
+	chunkSize int
+	jobs      int
+	padding   Padding
+	buf       []byte
+	out       []byte
+	index     uint32
+	n         int64
+	err       error
+}
+
+// NewWriter returns a WriteCloser that encrypts everything written to it in
+// the chunked stream format and writes the result to w. Close must be called
+// to write the final chunk; it does not close w.
+func NewWriter(w io.Writer, password []byte, opts ...Option) (io.WriteCloser, error) {
+	return NewWriterContext(context.Background(), w, password, opts...)
+}
+
+// NewWriterContext is like NewWriter but stops with ctx.Err() once ctx is
+// done. Cancellation is checked around key derivation and between chunks.
+func NewWriterContext(ctx context.Context, w io.Writer, password []byte, opts ...Option) (io.WriteCloser, error) {
+	o := newOptions(opts)
+	if len(password) == 0 && o.keyFunc == nil {
+		return nil, fmt.Errorf("password cannot be blank")
+	}
+	if o.chunkSize <= 0 || o.chunkSize > MaxChunkSize {
+		return nil, fmt.Errorf("invalid chunk size %d", o.chunkSize)
+	}
+	if o.padding.scheme == padBlock && o.padding.block < 1 {
+		return nil, fmt.Errorf("invalid padding block size %d", o.padding.block)
+	}
+	if o.compress > CompressGzip {
+		return nil, fmt.Errorf("unsupported compression %d", o.compress)
+	}
+
+	p, err := NewKeyParams()
+	if err != nil {
+		return nil, err
+	}
+	if o.keyParams != nil {
+		salt := p.Salt
+		p = *o.keyParams
+		if p.Salt == nil {
+			p.Salt = salt
+		}
+	}
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+
+	h := &header{
+		version:   streamVersion,
+		cipher:    cipherAESGCM,
+		kdf:       kdfArgon2id,
+		time:      p.Time,
+		memory:    p.Memory,
+		threads:   p.Threads,
+		chunkSize: uint32(o.chunkSize),
+		padding:   o.padding.scheme,
+		compress:  uint8(o.compress),
+	}
+	copy(h.salt[:], p.Salt)
+	if _, err := rand.Read(h.seed[:]); err != nil {
+		return nil, err
+	}
+
+	keys, err := deriveStreamKeys(ctx, h, password, o)
+	if err != nil {
+		return nil, err
+	}
+
+	b := h.marshal()
+	b = append(b, keys.headerMAC(b)...)
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+
+	sw := newStreamWriter(ctx, w, keys, o)
+	if o.compress != CompressNone {
+		return newCompressWriter(sw, o.compress)
+	}
+	return sw, nil
+}
+
+func newStreamWriter(ctx context.Context, w io.Writer, keys *streamKeys, o *options) *writer {
+	return &writer{
+		ctx:       ctx,
+		w:         w,
+		aead:      keys.aead,
+		chunkSize: o.chunkSize,
+		jobs:      o.jobs,
+		padding:   o.padding,
+		buf:       make([]byte, 0, o.jobs*o.chunkSize),
+	}
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	n := 0
+	for len(p) > 0 {
+		// Full chunks are only sealed once more data arrives, because the
+		// final chunk must be flagged as such.
+		if len(w.buf) == cap(w.buf) {
+			if err := w.seal(false); err != nil {
+				return n, err
+			}
+		}
+		m := copy(w.buf[len(w.buf):cap(w.buf)], p)
+		w.buf = w.buf[:len(w.buf)+m]
+		p = p[m:]
+		n += m
+	}
+	w.n += int64(n)
+	return n, nil
+}
+
+func (w *writer) Close() error {
+	if w.err != nil {
+		if w.err == errClosed {
+			return nil
+		}
+		return w.err
+	}
+	if w.padding != PadNone {
+		if err := w.pad(); err != nil {
+			return err
+		}
+	}
+	if err := w.seal(true); err != nil {
+		return err
+	}
+	w.err = errClosed
+	return nil
+}
+
+func (w *writer) pad() error {
+	n := w.padding.padLength(w.n)
+	zeros := make([]byte, min(n, int64(w.chunkSize)))
+	zeros[0] = 0x80
+	for n > 0 {
+		m, err := w.Write(zeros[:min(n, int64(len(zeros)))])
+		if err != nil {
+			return err
+		}
+		zeros[0] = 0
+		n -= int64(m)
+	}
+	return nil
+}
+
+// seal encrypts the buffered chunks, up to jobs of them concurrently, and
+// writes them out in order.
+func (w *writer) seal(last bool) error {
+	if err := w.ctx.Err(); err != nil {
+		w.err = err
+		return err
+	}
+
+	n := (len(w.buf) + w.chunkSize - 1) / w.chunkSize
+	if n == 0 {
+		n = 1
+	}
+	if end := uint64(w.index) + uint64(n); end > 1<<32 || !last && end == 1<<32 {
+		w.err = fmt.Errorf("stream too long")
+		return w.err
+	}
+
+	size := w.chunkSize + tagLength
+	w.out = slices.Grow(w.out[:0], len(w.buf)+n*tagLength)[:len(w.buf)+n*tagLength]
+	parallel(n, w.jobs, func(i int) error {
+		chunk := w.buf[i*w.chunkSize : min((i+1)*w.chunkSize, len(w.buf))]
+		nonce := chunkNonce(make([]byte, w.aead.NonceSize()), w.index+uint32(i), last && i == n-1)
+		w.aead.Seal(w.out[i*size:i*size], nonce, chunk, nil)
+		return nil
+	})
+
+	if _, err := w.w.Write(w.out); err != nil {
+		w.err = err
+		return err
+	}
+	w.buf = w.buf[:0]
+	w.index += uint32(n)
+	return nil
+}
+
+var errClosed = fmt.Errorf("write to closed stream")
+
+type reader struct {
+	ctx   context.Context
+	r     *bufio.Reader
+	aead  cipher.AEAD
+	size  int
+	jobs  int
+	pad   *unpadder
+	in    []byte
+	buf   []byte
+	out   []byte
+	index uint32
+	err   error
+}
+
+// NewReader returns a Reader that decrypts a stream written by NewWriter.
+// Every chunk is authenticated before any of its plaintext is returned.
+// Input without a stream header is treated as a legacy blob produced by
+// Encrypt; it is read in full and decrypted with Decrypt.
+func NewReader(r io.Reader, password []byte, opts ...Option) (io.Reader, error) {
+	return NewReaderContext(context.Background(), r, password, opts...)
+}
+
+// NewReaderContext is like NewReader but stops with ctx.Err() once ctx is
+// done. Cancellation is checked around key derivation and between chunks.
+func NewReaderContext(ctx context.Context, r io.Reader, password []byte, opts ...Option) (io.Reader, error) {
+	o := newOptions(opts)
+	if o.maxSize < 0 {
+		return nil, fmt.Errorf("invalid maximum size %d", o.maxSize)
+	}
+	r, err := newReader(ctx, r, password, o)
+	if err != nil {
+		return nil, err
+	}
+	if o.maxSize > 0 {
+		return &limitReader{r: r, n: o.maxSize}, nil
+	}
+	return r, nil
+}
+
+func newReader(ctx context.Context, r io.Reader, password []byte, o *options) (io.Reader, error) {
+	br := bufio.NewReader(r)
+
+	b, err := br.Peek(headerLength)
+	if !bytes.HasPrefix(b, magic) {
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		data, err := io.ReadAll(br)
+		if err != nil {
+			return nil, err
+		}
+		plaintext, err := decryptLegacy(ctx, data, password, o)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(plaintext), nil
+	}
+	if err != nil {
+		if err == io.EOF {
+			return nil, corruptf("invalid stream header")
+		}
+		return nil, err
+	}
+
+	h, keys, err := openHeader(ctx, b, password, o)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := br.Discard(headerLength); err != nil {
+		return nil, err
+	}
+
+	sr := newStreamReader(ctx, br, h, keys, o)
+	if h.compress != uint8(CompressNone) {
+		return newDecompressReader(sr, Compression(h.compress))
+	}
+	return sr, nil
+}
+
+func newStreamReader(ctx context.Context, src io.Reader, h *header, keys *streamKeys, o *options) *reader {
+	size := int(h.chunkSize) + tagLength
+	r := &reader{
+		ctx:  ctx,
+		r:    bufio.NewReaderSize(src, size+1),
+		aead: keys.aead,
+		size: size,
+		jobs: o.jobs,
+	}
+	if h.padding != padNone {
+		r.pad = &unpadder{}
+	}
+	return r
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.err = r.next()
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// next reads up to jobs chunks, decrypts them concurrently into r.buf and
+// returns io.EOF once the final chunk has been decrypted.
+func (r *reader) next() error {
+	if err := r.ctx.Err(); err != nil {
+		return err
+	}
+
+	r.in = r.in[:0]
+	n, last := 0, false
+	for n < r.jobs && !last {
+		// Peeking one byte past a full chunk tells whether it is the last one.
+		b, err := r.r.Peek(r.size + 1)
+		if err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+		if last {
+			if len(b) < tagLength {
+				return corruptf("ciphertext too short")
+			}
+		} else {
+			b = b[:r.size]
+		}
+		if end := uint64(r.index) + uint64(n+1); end > 1<<32 || !last && end == 1<<32 {
+			return corruptf("stream too long")
+		}
+
+		r.in = append(r.in, b...)
+		if _, err := r.r.Discard(len(b)); err != nil {
+			return err
+		}
+		n++
+	}
+
+	chunkSize := r.size - tagLength
+	r.out = slices.Grow(r.out[:0], len(r.in)-n*tagLength)[:len(r.in)-n*tagLength]
+	err := parallel(n, r.jobs, func(i int) error {
+		chunk := r.in[i*r.size : min((i+1)*r.size, len(r.in))]
+		nonce := chunkNonce(make([]byte, r.aead.NonceSize()), r.index+uint32(i), last && i == n-1)
+		if _, err := r.aead.Open(r.out[i*chunkSize:i*chunkSize], nonce, chunk, nil); err != nil {
+			return corruptf("corrupt chunk %d: %w", r.index+uint32(i), err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	r.buf = r.out
+	if r.pad != nil {
+		if r.buf, err = r.pad.unpad(r.out, last); err != nil {
+			return err
+		}
+	}
+	r.index += uint32(n)
+	if last {
+		return io.EOF
+	}
+	return nil
+}
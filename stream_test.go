@@ -0,0 +1,171 @@
+package argon2aes
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func encryptStream(t *testing.T, data, password []byte, opts ...Option) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, password, opts...)
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestStreamRoundTrip(t *testing.T) {
+	password := []byte("password")
+	testCases := []struct {
+		name string
+		data []byte
+	}{
+		{"Empty", []byte{}},
+		{"Short", []byte("Hello, World!")},
+		{"ExactChunk", bytes.Repeat([]byte("A"), 64)},
+		{"TwoChunks", bytes.Repeat([]byte("B"), 128)},
+		{"Partial", bytes.Repeat([]byte("C"), 150)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			encrypted := encryptStream(t, tc.data, password, WithChunkSize(64))
+			if !bytes.HasPrefix(encrypted, Magic()) {
+				t.Errorf("Stream does not start with %q", Magic())
+			}
+
+			r, err := NewReader(bytes.NewReader(encrypted), password)
+			if err != nil {
+				t.Fatalf("NewReader failed: %v", err)
+			}
+			decrypted, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll failed: %v", err)
+			}
+			if !bytes.Equal(tc.data, decrypted) {
+				t.Errorf("Decrypted data doesn't match original. Original: %v, Decrypted: %v", tc.data, decrypted)
+			}
+
+			decrypted, err = Decrypt(encrypted, password)
+			if err != nil {
+				t.Fatalf("Decrypt failed: %v", err)
+			}
+			if !bytes.Equal(tc.data, decrypted) {
+				t.Errorf("Decrypt doesn't match original. Original: %v, Decrypted: %v", tc.data, decrypted)
+			}
+		})
+	}
+}
+
+func TestStreamSmallWrites(t *testing.T) {
+	password := []byte("password")
+	data := bytes.Repeat([]byte("0123456789"), 50)
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, password, WithChunkSize(64))
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	for i := 0; i < len(data); i += 7 {
+		if _, err := w.Write(data[i:min(i+7, len(data))]); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if buf.Len() != headerLength+len(data)+8*tagLength {
+		t.Errorf("Unexpected stream length %d", buf.Len())
+	}
+
+	decrypted, err := Decrypt(buf.Bytes(), password)
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if !bytes.Equal(data, decrypted) {
+		t.Error("Decrypted data doesn't match original")
+	}
+}
+
+func TestStreamLegacyInput(t *testing.T) {
+	password := []byte("password")
+	data := []byte("legacy blob")
+
+	encrypted, err := Encrypt(data, password)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+
+	r, err := NewReader(bytes.NewReader(encrypted), password)
+	if err != nil {
+		t.Fatalf("NewReader failed: %v", err)
+	}
+	decrypted, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll failed: %v", err)
+	}
+	if !bytes.Equal(data, decrypted) {
+		t.Errorf("Decrypted data doesn't match original. Original: %v, Decrypted: %v", data, decrypted)
+	}
+}
+
+func TestStreamTampering(t *testing.T) {
+	password := []byte("password")
+	data := bytes.Repeat([]byte("D"), 200)
+	encrypted := encryptStream(t, data, password, WithChunkSize(64))
+
+	testCases := []struct {
+		name   string
+		mutate func([]byte) []byte
+		err    error
+	}{
+		{"WrongPassword", func(b []byte) []byte { return b }, ErrWrongPassword},
+		{"Header", func(b []byte) []byte { b[headerMACOffset-1]++; return b }, ErrWrongPassword},
+		{"Magic", func(b []byte) []byte { b[len(magic)]++; return b }, ErrCorrupt},
+		{"Chunk", func(b []byte) []byte { b[headerLength+10]++; return b }, ErrCorrupt},
+		{"Truncated", func(b []byte) []byte { return b[:headerLength+2*(64+tagLength)] }, ErrCorrupt},
+		{"DroppedChunk", func(b []byte) []byte {
+			return append(b[:headerLength:headerLength], b[headerLength+64+tagLength:]...)
+		}, ErrCorrupt},
+		{"Appended", func(b []byte) []byte { return append(b, 0) }, ErrCorrupt},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pw := password
+			if tc.name == "WrongPassword" {
+				pw = []byte("wrong password")
+			}
+			b := tc.mutate(bytes.Clone(encrypted))
+
+			if _, err := Decrypt(b, pw); err == nil {
+				t.Error("Expected an error when decrypting a modified stream, but got none")
+			} else if !errors.Is(err, tc.err) {
+				t.Errorf("Expected %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestNewWriterErrors(t *testing.T) {
+	if _, err := NewWriter(io.Discard, []byte{}); err == nil {
+		t.Error("Expected an error for a blank password, but got none")
+	}
+	if _, err := NewWriter(io.Discard, []byte("password"), WithChunkSize(0)); err == nil {
+		t.Error("Expected an error for a zero chunk size, but got none")
+	}
+	if _, err := NewWriter(io.Discard, []byte("password"), WithChunkSize(MaxChunkSize+1)); err == nil {
+		t.Error("Expected an error for an oversized chunk size, but got none")
+	}
+}
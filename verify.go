@@ -0,0 +1,22 @@
+package argon2aes
+
+import (
+	"context"
+	"io"
+)
+
+// Verify checks that r decrypts with password by authenticating every
+// chunk, without keeping any of the plaintext.
+func Verify(r io.Reader, password []byte, opts ...Option) error {
+	return VerifyContext(context.Background(), r, password, opts...)
+}
+
+// VerifyContext is like Verify but returns ctx.Err() once ctx is done.
+func VerifyContext(ctx context.Context, r io.Reader, password []byte, opts ...Option) error {
+	dr, err := NewReaderContext(ctx, r, password, opts...)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(io.Discard, dr)
+	return err
+}
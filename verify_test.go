@@ -0,0 +1,44 @@
+package argon2aes
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	password := []byte("password")
+	data := bytes.Repeat([]byte("K"), 1000)
+
+	stream := encryptStream(t, data, password, WithChunkSize(256))
+	legacy, err := Encrypt(data, password)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+
+	corrupt := bytes.Clone(stream)
+	corrupt[len(corrupt)-1]++
+
+	testCases := []struct {
+		name     string
+		data     []byte
+		password []byte
+		ok       bool
+	}{
+		{"Stream", stream, password, true},
+		{"Legacy", legacy, password, true},
+		{"WrongPassword", stream, []byte("wrong password"), false},
+		{"CorruptFinalChunk", corrupt, password, false},
+		{"Truncated", stream[:len(stream)-256], password, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Verify(bytes.NewReader(tc.data), tc.password, WithJobs(2))
+			if tc.ok && err != nil {
+				t.Errorf("Verify failed: %v", err)
+			} else if !tc.ok && err == nil {
+				t.Error("Expected an error, but got none")
+			}
+		})
+	}
+}